@@ -0,0 +1,265 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+	"math"
+)
+
+// Typed accessors convert between worksheet values, and native Go values.
+// Getters return whether the field is set, i.e. not undefined, alongside its
+// value.
+//
+// Fields whose type is a single field worksheet, e.g.
+//
+//	type name_suffix worksheet {
+//		1:suffix text
+//	}
+//
+// are automatically 'boxed', and 'unboxed': getters read the single field of
+// the worksheet referenced, and setters set a new worksheet holding the value.
+
+// GetText gets the value of a text, or enum, field.
+func (ws *Worksheet) GetText(name string) (string, bool, error) {
+	field, value, err := ws.getUnboxed(name)
+	if err != nil {
+		return "", false, err
+	}
+	switch field.typ.(type) {
+	case *TextType, *EnumType:
+	default:
+		return "", false, fmt.Errorf("GetText on field %s of type %s", name, field.typ)
+	}
+	if text, ok := value.(*Text); ok {
+		return text.value, true, nil
+	}
+	return "", false, nil
+}
+
+// GetBool gets the value of a bool field.
+func (ws *Worksheet) GetBool(name string) (bool, bool, error) {
+	field, value, err := ws.getUnboxed(name)
+	if err != nil {
+		return false, false, err
+	}
+	if _, ok := field.typ.(*BoolType); !ok {
+		return false, false, fmt.Errorf("GetBool on field %s of type %s", name, field.typ)
+	}
+	if b, ok := value.(*Bool); ok {
+		return b.value, true, nil
+	}
+	return false, false, nil
+}
+
+// GetNumberAsInt64 gets the value of a number field, which must not have a
+// fractional part.
+func (ws *Worksheet) GetNumberAsInt64(name string) (int64, bool, error) {
+	field, value, err := ws.getUnboxed(name)
+	if err != nil {
+		return 0, false, err
+	}
+	if _, ok := field.typ.(*NumberType); !ok {
+		return 0, false, fmt.Errorf("GetNumberAsInt64 on field %s of type %s", name, field.typ)
+	}
+	num, ok := value.(*Number)
+	if !ok {
+		return 0, false, nil
+	}
+	rounded := num.Round(ModeDown, 0)
+	if !rounded.Equal(num) {
+		return 0, false, fmt.Errorf("GetNumberAsInt64 on field %s with fractional value %s", name, num)
+	}
+	return rounded.value, true, nil
+}
+
+// GetNumberAsString gets the value of a number field, e.g. `5.20` for a
+// `number[2]`.
+func (ws *Worksheet) GetNumberAsString(name string) (string, bool, error) {
+	field, value, err := ws.getUnboxed(name)
+	if err != nil {
+		return "", false, err
+	}
+	typ, ok := field.typ.(*NumberType)
+	if !ok {
+		return "", false, fmt.Errorf("GetNumberAsString on field %s of type %s", name, field.typ)
+	}
+	num, ok := value.(*Number)
+	if !ok {
+		return "", false, nil
+	}
+	if num.typ.scale < typ.scale {
+		num = num.Round(ModeDown, typ.scale)
+	}
+	return num.String(), true, nil
+}
+
+// GetWorksheet gets the value of a worksheet field.
+func (ws *Worksheet) GetWorksheet(name string) (*Worksheet, bool, error) {
+	defer ws.rlock()()
+
+	field, value, err := ws.get(name)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, ok := field.typ.(*Definition); !ok {
+		return nil, false, fmt.Errorf("GetWorksheet on field %s of type %s", name, field.typ)
+	}
+	if v, ok := value.(*Worksheet); ok {
+		return v, true, nil
+	}
+	return nil, false, nil
+}
+
+// GetSliceOfWorksheets gets the elements of a slice of worksheets field.
+func (ws *Worksheet) GetSliceOfWorksheets(name string) ([]*Worksheet, error) {
+	defer ws.rlock()()
+
+	field, slice, err := ws.getSlice(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := field.typ.(*SliceType).elementType.(*Definition); !ok {
+		return nil, fmt.Errorf("GetSliceOfWorksheets on field %s of type %s", name, field.typ)
+	}
+	var wss []*Worksheet
+	for _, element := range slice.elements {
+		if elementWs, ok := element.value.(*Worksheet); ok {
+			wss = append(wss, elementWs)
+		}
+	}
+	return wss, nil
+}
+
+// SetText sets a text, or enum, field.
+func (ws *Worksheet) SetText(name string, value string) error {
+	return ws.setBoxed(name, NewText(value))
+}
+
+// SetBool sets a bool field.
+func (ws *Worksheet) SetBool(name string, value bool) error {
+	return ws.setBoxed(name, NewBool(value))
+}
+
+// SetNumberFromInt64 sets a number field, provided the value can be
+// represented at the field's scale.
+func (ws *Worksheet) SetNumberFromInt64(name string, value int64) error {
+	field, ok := ws.def.fieldsByName[name]
+	if !ok {
+		return &UnknownField{ws, name}
+	}
+	if inner, ok := boxedField(field.typ); ok {
+		field = inner
+	}
+	if typ, ok := field.typ.(*NumberType); ok {
+		bound := int64(math.MaxInt64)
+		for i := 0; i < typ.scale; i++ {
+			bound /= 10
+		}
+		if value < -bound || bound < value {
+			return fmt.Errorf("cannot assign %d to %s, out of range", value, typ)
+		}
+	}
+	return ws.setBoxed(name, NewNumberFromInt64(value))
+}
+
+// SetNumberFromString sets a number field from its representation, e.g.
+// `5.20`, whose scale must not exceed the field's scale.
+func (ws *Worksheet) SetNumberFromString(name string, value string) error {
+	number, err := NewNumberFromString(value)
+	if err != nil {
+		return err
+	}
+	return ws.setBoxed(name, number)
+}
+
+// SetWorksheet sets a worksheet field.
+func (ws *Worksheet) SetWorksheet(name string, value *Worksheet) error {
+	if value == nil {
+		return ws.Unset(name)
+	}
+	return ws.Set(name, value)
+}
+
+// SetSliceOfWorksheets replaces the elements of a slice of worksheets field, as
+// a single edit.
+func (ws *Worksheet) SetSliceOfWorksheets(name string, values []*Worksheet) error {
+	e := NewEdit().Clear(name)
+	for _, value := range values {
+		e.Append(name, value)
+	}
+	return e.Apply(ws)
+}
+
+// boxedField returns the single field of worksheets of type `typ`, if `typ`
+// is a single field worksheet.
+func boxedField(typ Type) (*Field, bool) {
+	def, ok := typ.(*Definition)
+	if !ok {
+		return nil, false
+	}
+	var single *Field
+	for index, field := range def.fieldsByIndex {
+		if 0 < index {
+			if single != nil {
+				return nil, false
+			}
+			single = field
+		}
+	}
+	return single, single != nil
+}
+
+// getUnboxed gets the value of a field, unboxing single field worksheets.
+func (ws *Worksheet) getUnboxed(name string) (*Field, Value, error) {
+	defer ws.rlock()()
+
+	field, value, err := ws.get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	inner, ok := boxedField(field.typ)
+	if !ok {
+		return field, value, nil
+	}
+	box, ok := value.(*Worksheet)
+	if !ok {
+		return inner, vUndefined, nil
+	}
+	value, ok = box.data[inner.index]
+	if !ok {
+		value = vUndefined
+	}
+	return inner, value, nil
+}
+
+// setBoxed sets a field, boxing the value in a new single field worksheet
+// when needed.
+func (ws *Worksheet) setBoxed(name string, value Value) error {
+	field, ok := ws.def.fieldsByName[name]
+	if !ok {
+		return &UnknownField{ws, name}
+	}
+	inner, ok := boxedField(field.typ)
+	if !ok {
+		return ws.Set(name, value)
+	}
+	box, err := field.typ.(*Definition).newUninitializedWorksheet().initialize()
+	if err != nil {
+		return err
+	}
+	if err := box.Set(inner.name, value); err != nil {
+		return err
+	}
+	return ws.Set(name, box)
+}
@@ -0,0 +1,179 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"math"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defsForAccessors = `
+type person worksheet {
+	1:name     text
+	2:age      number[0]
+	3:income   number[2]
+	4:married  bool
+	5:suffix   name_suffix
+	6:spouse   person
+	7:children []person
+	8:color    color
+}
+
+type name_suffix worksheet {
+	1:suffix text constrained_by {
+		return suffix == "Jr." || suffix == "Sr."
+	}
+}
+
+type color enum {
+	"red",
+	"blue",
+}`
+
+func (s *Zuite) TestAccessors_getAndSet() {
+	defs := MustNewDefinitions(strings.NewReader(defsForAccessors))
+	ws := defs.MustNewWorksheet("person")
+
+	text, ok, err := ws.GetText("name")
+	require.NoError(s.T(), err)
+	require.False(s.T(), ok)
+	require.Equal(s.T(), "", text)
+
+	require.NoError(s.T(), ws.SetText("name", "Alice"))
+	require.NoError(s.T(), ws.SetNumberFromInt64("age", 42))
+	require.NoError(s.T(), ws.SetNumberFromString("income", "1234.5"))
+	require.NoError(s.T(), ws.SetBool("married", true))
+	require.NoError(s.T(), ws.SetText("color", "blue"))
+
+	text, ok, err = ws.GetText("name")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "Alice", text)
+
+	age, ok, err := ws.GetNumberAsInt64("age")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), int64(42), age)
+
+	income, ok, err := ws.GetNumberAsString("income")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "1234.50", income)
+
+	married, ok, err := ws.GetBool("married")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.True(s.T(), married)
+
+	color, ok, err := ws.GetText("color")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "blue", color)
+}
+
+func (s *Zuite) TestAccessors_numbers() {
+	defs := MustNewDefinitions(strings.NewReader(defsForAccessors))
+	ws := defs.MustNewWorksheet("person")
+
+	require.NoError(s.T(), ws.SetNumberFromInt64("income", 100))
+	income, _, err := ws.GetNumberAsInt64("income")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(100), income)
+
+	require.NoError(s.T(), ws.SetNumberFromString("income", "100.25"))
+	_, _, err = ws.GetNumberAsInt64("income")
+	require.EqualError(s.T(), err, "GetNumberAsInt64 on field income with fractional value 100.25")
+
+	cases := map[string]error{
+		"cannot assign value of type number[3] to number[2]":           ws.SetNumberFromString("income", "1.125"),
+		"cannot assign value of type number[2] to number[0]":           ws.SetNumberFromString("age", "1.50"),
+		"cannot assign 9223372036854775807 to number[2], out of range": ws.SetNumberFromInt64("income", math.MaxInt64),
+		"cannot assign value of type number[0] to text":                ws.SetNumberFromInt64("name", 5),
+	}
+	for msg, err := range cases {
+		require.EqualError(s.T(), err, msg)
+	}
+	require.Equal(s.T(), "100.25", ws.MustGet("income").String())
+}
+
+func (s *Zuite) TestAccessors_typeMismatches() {
+	defs := MustNewDefinitions(strings.NewReader(defsForAccessors))
+	ws := defs.MustNewWorksheet("person")
+
+	_, _, err := ws.GetText("age")
+	require.EqualError(s.T(), err, "GetText on field age of type number[0]")
+	_, _, err = ws.GetBool("name")
+	require.EqualError(s.T(), err, "GetBool on field name of type text")
+	_, _, err = ws.GetNumberAsString("spouse")
+	require.EqualError(s.T(), err, "GetNumberAsString on field spouse of type person")
+	_, _, err = ws.GetWorksheet("children")
+	require.EqualError(s.T(), err, "GetWorksheet on field children of type []person")
+	_, err = ws.GetSliceOfWorksheets("name")
+	require.EqualError(s.T(), err, "GetSlice on non-slice field name, use Get")
+	_, _, err = ws.GetText("not_a_field")
+	require.EqualError(s.T(), err, "unknown field not_a_field")
+}
+
+func (s *Zuite) TestAccessors_worksheets() {
+	defs := MustNewDefinitions(strings.NewReader(defsForAccessors))
+	ws := defs.MustNewWorksheet("person")
+	spouse := defs.MustNewWorksheet("person")
+	alice, bob := defs.MustNewWorksheet("person"), defs.MustNewWorksheet("person")
+
+	require.NoError(s.T(), ws.SetWorksheet("spouse", spouse))
+	got, ok, err := ws.GetWorksheet("spouse")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), spouse, got)
+
+	require.NoError(s.T(), ws.SetWorksheet("spouse", nil))
+	_, ok, err = ws.GetWorksheet("spouse")
+	require.NoError(s.T(), err)
+	require.False(s.T(), ok)
+
+	require.NoError(s.T(), ws.SetSliceOfWorksheets("children", []*Worksheet{alice, bob}))
+	require.NoError(s.T(), ws.SetSliceOfWorksheets("children", []*Worksheet{bob}))
+	children, err := ws.GetSliceOfWorksheets("children")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []*Worksheet{bob}, children)
+	require.Empty(s.T(), alice.parents["person"])
+}
+
+func (s *Zuite) TestAccessors_boxing() {
+	defs := MustNewDefinitions(strings.NewReader(defsForAccessors))
+	ws := defs.MustNewWorksheet("person")
+
+	_, ok, err := ws.GetText("suffix")
+	require.NoError(s.T(), err)
+	require.False(s.T(), ok)
+
+	require.NoError(s.T(), ws.SetText("suffix", "Jr."))
+	suffix, ok, err := ws.GetText("suffix")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "Jr.", suffix)
+
+	box, ok, err := ws.GetWorksheet("suffix")
+	require.NoError(s.T(), err)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "name_suffix", box.Name())
+	require.Equal(s.T(), `"Jr."`, box.MustGet("suffix").String())
+
+	// Boxed values are subject to the constraints of the box.
+	err = ws.SetText("suffix", "III")
+	require.EqualError(s.T(), err, `"III" not a valid value for constrained field suffix`)
+	suffix, _, _ = ws.GetText("suffix")
+	require.Equal(s.T(), "Jr.", suffix)
+}
@@ -2,6 +2,7 @@ package worksheets
 
 import (
 	"database/sql"
+	"fmt"
 	"math/rand"
 	"strings"
 	"testing"
@@ -131,3 +132,32 @@ func BenchmarkLoad(_b *testing.B) {
 		}
 	}
 }
+
+// diamondDefs defines a worksheet with layers of diamond-shaped dependencies,
+// where each layer depends twice on the previous one. Recursively cascading
+// updates would recompute the last layer 2^layers times per edit.
+func diamondDefs(layers int) string {
+	src := "type diamonds worksheet {\n\t1:l0 number[0]\n"
+	index := 2
+	for i := 1; i <= layers; i++ {
+		src += fmt.Sprintf("\t%d:l%d_left number[0] computed_by { return l%d + 1 }\n", index, i, i-1)
+		src += fmt.Sprintf("\t%d:l%d_right number[0] computed_by { return l%d - 1 }\n", index+1, i, i-1)
+		src += fmt.Sprintf("\t%d:l%d number[0] computed_by { return (l%d_left + l%d_right) / 2 round down 0 }\n", index+2, i, i, i)
+		index += 3
+	}
+	return src + "}"
+}
+
+func BenchmarkRecompute_diamonds(b *testing.B) {
+	for _, layers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("layers=%d", layers), func(b *testing.B) {
+			defs := MustNewDefinitions(strings.NewReader(diamondDefs(layers)))
+			ws := defs.MustNewWorksheet("diamonds")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ws.MustSet("l0", NewNumberFromInt(i))
+			}
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 // Clone duplicates this worksheet, and all worksheets it points to, in order
 // to create a deep-copy.
 func (ws *Worksheet) Clone() *Worksheet {
+	defer ws.rlock()()
+
 	c := &cloner{
 		mapping: make(map[string]string),
 		clones:  make(map[string]*Worksheet),
@@ -35,6 +37,10 @@ type cloner struct {
 
 	// clones records all dupped worksheets by their ids
 	clones map[string]*Worksheet
+
+	// graph is the graph lock shared by all dupped worksheets, when
+	// concurrency safe.
+	graph *graphLock
 }
 
 func (c *cloner) clone(parent *Worksheet, index int, value Value) Value {
@@ -74,7 +80,9 @@ func (c *cloner) cloneWs(ws *Worksheet) *Worksheet {
 	// its version set at 1.
 
 	dup := ws.def.newUninitializedWorksheet()
-	dup.data[indexId] = NewText(uuid.Must(uuid.NewV4()).String())
+	shareGraph(&c.graph, dup)
+	dup.id = uuid.Must(uuid.NewV4()).String()
+	dup.data[indexId] = NewText(dup.id)
 	dup.data[indexVersion] = NewNumberFromInt(1)
 	c.mapping[ws.Id()] = dup.Id()
 	c.clones[dup.Id()] = dup
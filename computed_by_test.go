@@ -318,24 +318,106 @@ func (s *Zuite) TestComputedBy_simpleExpressionsInWorksheet() {
 	require.Equal(s.T(), "75", ws.MustGet("age_plus_two").String())
 }
 
-func (s *Zuite) TestComputedBy_cyclicEditsIfNoIdentCheck() {
-	defs, err := NewDefinitions(strings.NewReader(`type cyclic_edits worksheet {
-		1:right bool
-		2:a bool computed_by {
-			return b || right
+func (s *Zuite) TestComputedBy_cyclicDependencies() {
+	cases := map[string]string{
+		`type cyclic_edits worksheet {
+			1:right bool
+			2:a bool computed_by {
+				return b || right
+			}
+			3:b bool computed_by {
+				return a || !right
+			}
+		}`: "cyclic dependency: cyclic_edits.a -> cyclic_edits.b -> cyclic_edits.a",
+
+		`type self worksheet {
+			1:count number[0] computed_by {
+				return count + 1
+			}
+		}`: "cyclic dependency: self.count -> self.count",
+
+		`type triangle worksheet {
+			1:a number[0] computed_by { return c }
+			2:b number[0] computed_by { return a }
+			3:c number[0] computed_by { return b }
+		}`: "cyclic dependency: triangle.a -> triangle.c -> triangle.b -> triangle.a",
+
+		`type loan worksheet {
+			1:amount   number[0]
+			2:property property
+			3:ltv      number[2] computed_by {
+				return amount / property.value round down 2
+			}
 		}
-		3:b bool computed_by {
-			return a || !right
+
+		type property worksheet {
+			1:loan  loan
+			2:value number[0] computed_by {
+				return loan.ltv * 100 round down 0
+			}
+		}`: "cyclic dependency: loan.ltv -> property.value -> loan.ltv",
+
+		`type a worksheet {
+			1:b  []b
+			2:total number[0] computed_by { return sum(b.copy) }
 		}
-	}`))
-	require.NoError(s.T(), err)
 
-	ws := defs.MustNewWorksheet("cyclic_edits")
+		type b worksheet {
+			1:c    c
+			2:copy number[0] computed_by { return c.copy }
+		}
 
-	ws.MustSet("right", NewBool(true))
-	require.Equal(s.T(), "true", ws.MustGet("right").String(), "right")
-	require.Equal(s.T(), "undefined", ws.MustGet("a").String(), "a")
-	require.Equal(s.T(), "undefined", ws.MustGet("b").String(), "b")
+		type c worksheet {
+			1:a    a
+			2:copy number[0] computed_by { return a.total }
+		}`: "cyclic dependency: a.total -> b.copy -> c.copy -> a.total",
+	}
+	for defs, msg := range cases {
+		_, err := NewDefinitions(strings.NewReader(defs))
+		require.EqualError(s.T(), err, msg)
+	}
+}
+
+func (s *Zuite) TestComputedBy_recursiveAggregates() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type node worksheet {
+		1:amount   number[0]
+		2:children []node
+		3:total    number[0] computed_by {
+			return amount + sum(children.total)
+		}
+	}`))
+
+	// Each node's total depends on the total of other nodes, which is not a
+	// cycle as long as worksheets do not form one.
+	root := defs.MustNewWorksheet("node")
+	child := defs.MustNewWorksheet("node")
+	grandchild := defs.MustNewWorksheet("node")
+	root.MustSet("amount", NewNumberFromInt(1))
+	child.MustSet("amount", NewNumberFromInt(10))
+	grandchild.MustSet("amount", NewNumberFromInt(0))
+	child.MustAppend("children", grandchild)
+	root.MustAppend("children", child)
+	require.Equal(s.T(), "11", root.MustGet("total").String())
+
+	grandchild.MustSet("amount", NewNumberFromInt(100))
+	require.Equal(s.T(), "100", grandchild.MustGet("total").String())
+	require.Equal(s.T(), "110", child.MustGet("total").String())
+	require.Equal(s.T(), "111", root.MustGet("total").String())
+
+	child.MustDel("children", 0)
+	require.Equal(s.T(), "10", child.MustGet("total").String())
+	require.Equal(s.T(), "11", root.MustGet("total").String())
+
+	// Depending on itself directly is still a cycle.
+	_, err := NewDefinitions(strings.NewReader(`
+	type node worksheet {
+		1:children []node
+		2:total    number[0] computed_by {
+			return total + sum(children.total)
+		}
+	}`))
+	require.EqualError(s.T(), err, "cyclic dependency: node.total -> node.total")
 }
 
 var defsCrossWs = `
@@ -935,3 +1017,211 @@ func (s *Zuite) TestComputedBy_computedByOnNewInstance() {
 	ws := defs.MustNewWorksheet("sum_should_be_zero_on_new")
 	require.Equal(s.T(), NewNumberFromInt(0), ws.MustGet("sum"))
 }
+
+// failIfAbove is a plugin which mirrors its argument, and yields a value of
+// the wrong type, hence failing the edit, when the argument exceeds a
+// threshold.
+type failIfAbove struct {
+	arg       string
+	threshold int64
+}
+
+func (p failIfAbove) Args() []string {
+	return []string{p.arg}
+}
+
+func (p failIfAbove) Compute(values ...Value) Value {
+	if num, ok := values[0].(*Number); ok && p.threshold < num.value {
+		return NewText("too much")
+	}
+	return values[0]
+}
+
+var defsForFailingPlugins = `
+type order worksheet {
+	1:items   []item
+	2:total   number[0] computed_by {
+		return sum(items.checked)
+	}
+	3:audited number[0] computed_by { external }
+}
+
+type item worksheet {
+	1:price   number[0]
+	2:checked number[0] computed_by { external }
+}`
+
+func (s *Zuite) failingPluginsFixture() (*Definitions, *Worksheet, *Worksheet) {
+	defs := MustNewDefinitions(strings.NewReader(defsForFailingPlugins), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"order": {
+				"audited": failIfAbove{"total", 60},
+			},
+			"item": {
+				"checked": failIfAbove{"price", 50},
+			},
+		},
+	})
+	item := defs.MustNewWorksheet("item")
+	item.MustSet("price", MustNewValue("10"))
+	order := defs.MustNewWorksheet("order")
+	order.MustAppend("items", item)
+	return defs, order, item
+}
+
+func (s *Zuite) requireOrderUntouched(order, item *Worksheet) {
+	require.Equal(s.T(), []Value{item}, order.MustGetSlice("items"))
+	require.Equal(s.T(), "10", order.MustGet("total").String())
+	require.Equal(s.T(), "10", order.MustGet("audited").String())
+	require.Equal(s.T(), "10", item.MustGet("price").String())
+	require.Equal(s.T(), "10", item.MustGet("checked").String())
+	require.Len(s.T(), item.parents["order"], 1)
+}
+
+func (s *Zuite) TestComputedBy_failingPluginRollsBackSet() {
+	_, order, item := s.failingPluginsFixture()
+
+	// Fails on the item itself.
+	err := item.Set("price", MustNewValue("60"))
+	require.EqualError(s.T(), err, "cannot assign value of type text to number[0]")
+	s.requireOrderUntouched(order, item)
+
+	// Fails on the parent, after the item, and the order's total were
+	// recomputed. The item is in the order twice, which doubles its price.
+	order.MustAppend("items", item)
+	require.Equal(s.T(), "20", order.MustGet("total").String())
+	err = item.Set("price", MustNewValue("35"))
+	require.EqualError(s.T(), err, "cannot assign value of type text to number[0]")
+	require.Equal(s.T(), "10", item.MustGet("price").String())
+	require.Equal(s.T(), "10", item.MustGet("checked").String())
+	require.Equal(s.T(), "20", order.MustGet("total").String())
+	require.Equal(s.T(), "20", order.MustGet("audited").String())
+}
+
+func (s *Zuite) TestComputedBy_failingPluginRollsBackAppend() {
+	defs, order, item := s.failingPluginsFixture()
+
+	expensive := defs.MustNewWorksheet("item")
+	expensive.MustSet("price", MustNewValue("45"))
+	other := defs.MustNewWorksheet("item")
+	other.MustSet("price", MustNewValue("46"))
+
+	require.NoError(s.T(), order.Append("items", expensive))
+	err := order.Append("items", other)
+	require.EqualError(s.T(), err, "cannot assign value of type text to number[0]")
+
+	require.Equal(s.T(), []Value{item, expensive}, order.MustGetSlice("items"))
+	require.Equal(s.T(), "55", order.MustGet("total").String())
+	require.Empty(s.T(), other.parents)
+
+	// Since `other` was not added, editing it does not reach the order.
+	other.MustSet("price", MustNewValue("1"))
+	require.Equal(s.T(), "55", order.MustGet("total").String())
+}
+
+func (s *Zuite) TestComputedBy_failingPluginRollsBackDel() {
+	defs := MustNewDefinitions(strings.NewReader(defsForFailingPlugins), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"order": {
+				// A negative threshold fails whenever the total is set,
+				// i.e. as soon as it is not undefined.
+				"audited": failIfAbove{"total", -1},
+			},
+			"item": {
+				"checked": failIfAbove{"price", 50},
+			},
+		},
+	})
+	item := defs.MustNewWorksheet("item")
+	order := defs.MustNewWorksheet("order")
+	order.MustAppend("items", item)
+	require.Equal(s.T(), "undefined", order.MustGet("total").String())
+
+	// Deleting the only item makes the total 0, which fails.
+	err := order.Del("items", 0)
+	require.EqualError(s.T(), err, "cannot assign value of type text to number[0]")
+
+	require.Equal(s.T(), []Value{item}, order.MustGetSlice("items"))
+	require.Equal(s.T(), "undefined", order.MustGet("total").String())
+	require.Len(s.T(), item.parents["order"], 1)
+}
+
+// countingPlus is a plugin adding its arguments, and counting how many times
+// it is computed.
+type countingPlus struct {
+	args  []string
+	calls map[string]int
+	name  string
+}
+
+func (p countingPlus) Args() []string {
+	return p.args
+}
+
+func (p countingPlus) Compute(values ...Value) Value {
+	p.calls[p.name]++
+	sum := NewNumberFromInt(0)
+	for _, value := range values {
+		num, ok := value.(*Number)
+		if !ok {
+			return vUndefined
+		}
+		sum = sum.Plus(num)
+	}
+	return sum
+}
+
+var defsForDiamonds = `
+type top worksheet {
+	1:a     number[0]
+	2:left  number[0] computed_by { external }
+	3:right number[0] computed_by { external }
+	4:apex  number[0] computed_by { external }
+	5:sides []side
+	6:total number[0] computed_by { external }
+}
+
+type side worksheet {
+	1:b number[0]
+	2:c number[0] computed_by { external }
+}`
+
+func (s *Zuite) TestComputedBy_diamondsComputedOnce() {
+	calls := make(map[string]int)
+	plus := func(name string, args ...string) countingPlus {
+		return countingPlus{args, calls, name}
+	}
+	defs := MustNewDefinitions(strings.NewReader(defsForDiamonds), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"top": {
+				"left":  plus("left", "a"),
+				"right": plus("right", "a", "a"),
+				"apex":  plus("apex", "left", "right"),
+				"total": plus("total", "apex", "sides.c", "sides.b"),
+			},
+			"side": {
+				"c": plus("c", "b"),
+			},
+		},
+	})
+	side := defs.MustNewWorksheet("side")
+	side.MustSet("b", NewNumberFromInt(1))
+	top := defs.MustNewWorksheet("top")
+	top.MustAppend("sides", side)
+
+	// Each field is computed exactly once per edit, even though it is
+	// reachable from the edited field through several paths.
+	for k := range calls {
+		delete(calls, k)
+	}
+	top.MustSet("a", NewNumberFromInt(1))
+	require.Equal(s.T(), map[string]int{"left": 1, "right": 1, "apex": 1, "total": 1}, calls)
+	require.Equal(s.T(), "3", top.MustGet("apex").String())
+
+	// Across worksheets too, with both `c` and `b` triggering the total.
+	for k := range calls {
+		delete(calls, k)
+	}
+	side.MustSet("b", NewNumberFromInt(2))
+	require.Equal(s.T(), map[string]int{"c": 1, "total": 1}, calls)
+}
@@ -0,0 +1,197 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"sort"
+	"sync"
+)
+
+// graphLock is the lock of a graph of worksheets, i.e. of worksheets linked
+// by refs and slices, when worksheets are concurrency safe (see
+// `Options.Concurrent`). Since edits cascade from children to their parents,
+// the whole graph is locked when editing any of its worksheets.
+//
+// Each worksheet starts in its own graph. Graphs are merged when an edit links
+// worksheets of different graphs, and are never split. Merged graphs form a
+// union-find structure: a merged graph points to the graph it was merged
+// into, and the root of this structure holds the lock of all merged graphs.
+//
+// Reading a worksheet read locks its graph, editing it write locks its graph,
+// along with the graphs of all worksheets the edit links it to, and these
+// locks are acquired in a fixed order to avoid deadlocks.
+//
+// Editors and plugins are invoked with the graph locked, and are therefore
+// given snapshots of the worksheets they read. Listeners are only notified
+// once the graph is unlocked.
+type graphLock struct {
+	mu sync.RWMutex
+
+	// seq orders graphs, to lock them in a fixed order.
+	seq uint64
+
+	// merged is the graph this graph was merged into, if any, and is guarded
+	// by graphsMu.
+	merged *graphLock
+}
+
+var (
+	graphsMu  sync.Mutex
+	graphsSeq uint64
+)
+
+func newGraphLock() *graphLock {
+	graphsMu.Lock()
+	defer graphsMu.Unlock()
+	graphsSeq++
+	return &graphLock{seq: graphsSeq}
+}
+
+func (graph *graphLock) root() *graphLock {
+	graphsMu.Lock()
+	defer graphsMu.Unlock()
+	for graph.merged != nil {
+		graph = graph.merged
+	}
+	return graph
+}
+
+// shareGraph places ws in the graph `*shared`, or starts it with the graph of
+// ws. This is used when linking worksheets directly, rather than through
+// edits, e.g. when loading, or cloning worksheets.
+func shareGraph(shared **graphLock, ws *Worksheet) {
+	if ws.graph == nil {
+		return
+	}
+	if *shared == nil {
+		*shared = ws.graph
+	} else {
+		ws.graph = *shared
+	}
+}
+
+// rlock read locks the graph of the worksheet, and returns the function
+// unlocking it.
+func (ws *Worksheet) rlock() func() {
+	if ws.graph == nil {
+		return func() {}
+	}
+	for {
+		root := ws.graph.root()
+		root.mu.RLock()
+		if root.root() == root {
+			return root.mu.RUnlock
+		}
+		// The graph was merged while we were waiting.
+		root.mu.RUnlock()
+	}
+}
+
+// lock write locks the graph of the worksheet, and returns the function
+// unlocking it.
+func (ws *Worksheet) lock() func() {
+	unlock, _ := lockGraphs([]*Worksheet{ws})
+	return unlock
+}
+
+// lockGraphs write locks the graphs of all worksheets, and merges them. It
+// returns the function unlocking them, and the merged graph, which is nil if
+// worksheets are not concurrency safe.
+func lockGraphs(wss []*Worksheet) (func(), *graphLock) {
+	for {
+		var (
+			seen  = make(map[*graphLock]bool)
+			roots []*graphLock
+		)
+		for _, ws := range wss {
+			if ws.graph == nil {
+				continue
+			}
+			if root := ws.graph.root(); !seen[root] {
+				seen[root] = true
+				roots = append(roots, root)
+			}
+		}
+		if len(roots) == 0 {
+			return func() {}, nil
+		}
+		sort.Slice(roots, func(i, j int) bool {
+			return roots[i].seq < roots[j].seq
+		})
+		for _, root := range roots {
+			root.mu.Lock()
+		}
+		unlock := func() {
+			for i := len(roots) - 1; 0 <= i; i-- {
+				roots[i].mu.Unlock()
+			}
+		}
+
+		// Should any graph have been merged while we were waiting, we start
+		// over with the graphs they were merged into.
+		graphsMu.Lock()
+		stillRoots := true
+		for _, root := range roots {
+			stillRoots = stillRoots && root.merged == nil
+		}
+		if stillRoots {
+			for _, root := range roots[1:] {
+				root.merged = roots[0]
+			}
+		}
+		graphsMu.Unlock()
+		if stillRoots {
+			return unlock, roots[0]
+		}
+		unlock()
+	}
+}
+
+// unlockedWorksheets reports worksheets which an edit links to, but whose
+// graph is not locked.
+type unlockedWorksheets struct {
+	worksheets []*Worksheet
+}
+
+func (err *unlockedWorksheets) Error() string {
+	return "unexpected edit of unlocked worksheets"
+}
+
+// checkLocked verifies that the graphs of all worksheets which the edit links
+// to are locked by the transaction.
+func (tx *editTx) checkLocked(e *Edit) error {
+	if tx.graph == nil {
+		return nil
+	}
+	var unlocked []*Worksheet
+	for _, ws := range e.worksheets() {
+		if ws.graph != nil && ws.graph.root() != tx.graph {
+			unlocked = append(unlocked, ws)
+		}
+	}
+	if len(unlocked) != 0 {
+		return &unlockedWorksheets{unlocked}
+	}
+	return nil
+}
+
+// worksheets lists the worksheets the edit links to.
+func (e *Edit) worksheets() []*Worksheet {
+	var wss []*Worksheet
+	for _, op := range e.ops {
+		if op.value != nil {
+			wss = append(wss, extractChildWs(op.value)...)
+		}
+	}
+	return wss
+}
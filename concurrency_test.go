@@ -0,0 +1,244 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"strings"
+	"sync"
+
+	runner "github.com/homelight/dat/sqlx-runner"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *Zuite) TestConcurrent_editsOfParentsAndChildren() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit), Options{
+		Concurrent: true,
+	})
+	household := defs.MustNewWorksheet("household")
+	var members []*Worksheet
+	for i := 0; i < 4; i++ {
+		member := defs.MustNewWorksheet("member")
+		member.MustSet("income", NewNumberFromInt(0))
+		members = append(members, member)
+	}
+
+	var wg sync.WaitGroup
+	for _, member := range members {
+		wg.Add(1)
+		go func(member *Worksheet) {
+			defer wg.Done()
+			// Linking the member to the household merges their graphs,
+			// while other goroutines edit the household.
+			household.MustAppend("members", member)
+			for i := 1; i <= 50; i++ {
+				member.MustSet("income", NewNumberFromInt(i))
+				member.MustGet("total")
+				household.MustGet("total_income")
+			}
+		}(member)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			household.MustGetSlice("members")
+			household.Snapshot()
+		}
+	}()
+	wg.Wait()
+
+	require.Len(s.T(), household.MustGetSlice("members"), 4)
+	require.Equal(s.T(), "200", household.MustGet("total_income").String())
+}
+
+func (s *Zuite) TestConcurrent_linkingGraphsInBothDirections() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit), Options{
+		Concurrent: true,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		first, second := defs.MustNewWorksheet("household"), defs.MustNewWorksheet("household")
+		alice, bob := defs.MustNewWorksheet("member"), defs.MustNewWorksheet("member")
+		first.MustAppend("members", alice)
+		second.MustAppend("members", bob)
+
+		// Both edits lock both graphs, in opposite order of linking.
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			first.MustAppend("members", bob)
+		}()
+		go func() {
+			defer wg.Done()
+			second.MustAppend("members", alice)
+		}()
+		wg.Wait()
+
+		require.Equal(s.T(), first.graph.root(), second.graph.root())
+	}
+}
+
+func (s *Zuite) TestConcurrent_editorsAndListeners() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEditors), Options{
+		Concurrent: true,
+		Editors: map[string][]Editor{
+			"application": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					// Editors read a snapshot, hence do not deadlock.
+					if current.MustGet("complete").Equal(NewBool(true)) && !proposed.IsSetting("triggered") {
+						return proposed.Set("triggered", MustNewValue("1")), nil
+					}
+					return proposed, nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("application")
+	require.NoError(s.T(), ws.Subscribe([]string{"triggered"}, func(change Change) {
+		// Listeners are notified with the graph unlocked.
+		ws.MustSet("name", NewText("Notified"))
+	}))
+
+	err := NewEdit().
+		Set("name", NewText("Alice")).
+		Set("ssn", NewText("123")).
+		Set("income", MustNewValue("100")).
+		Apply(ws)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "1", ws.MustGet("triggered").String())
+	require.Equal(s.T(), `"Notified"`, ws.MustGet("name").String())
+}
+
+// sumOfIncomes is a plugin reading the worksheets it is given, and depending
+// on their incomes.
+type sumOfIncomes struct{}
+
+func (sumOfIncomes) Args() []string {
+	return []string{"members", "members.income"}
+}
+
+func (sumOfIncomes) Compute(values ...Value) Value {
+	sum := NewNumberFromInt(0)
+	for _, element := range values[0].(*Slice).Elements() {
+		income, ok := element.(*Worksheet).MustGet("income").(*Number)
+		if !ok {
+			return vUndefined
+		}
+		sum = sum.Plus(income)
+	}
+	return sum
+}
+
+func (s *Zuite) TestConcurrent_pluginsReadWorksheets() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type household worksheet {
+		1:members      []member
+		2:total_income number[0] computed_by { external }
+	}
+
+	type member worksheet {
+		1:income number[0]
+	}`), Options{
+		Concurrent: true,
+		Plugins: map[string]map[string]ComputedBy{
+			"household": {
+				"total_income": sumOfIncomes{},
+			},
+		},
+	})
+	household := defs.MustNewWorksheet("household")
+	alice := defs.MustNewWorksheet("member")
+	alice.MustSet("income", NewNumberFromInt(100))
+
+	// Plugins read snapshots, hence do not deadlock.
+	household.MustAppend("members", alice)
+	alice.MustSet("income", NewNumberFromInt(110))
+	require.Equal(s.T(), "110", household.MustGet("total_income").String())
+}
+
+func (s *Zuite) TestConcurrent_readsDuringEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit), Options{
+		Concurrent: true,
+	})
+	household := defs.MustNewWorksheet("household")
+	member := defs.MustNewWorksheet("member")
+	household.MustAppend("members", member)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 50; i++ {
+			member.MustSet("income", NewNumberFromInt(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_, err := household.Explain("total_income")
+			require.NoError(s.T(), err)
+			_, err = member.Impacts("income")
+			require.NoError(s.T(), err)
+			_, err = household.MarshalJSON()
+			require.NoError(s.T(), err)
+			household.Clone()
+		}
+	}()
+	wg.Wait()
+
+	require.Equal(s.T(), "50", household.MustGet("total_income").String())
+}
+
+func (s *Zuite) TestConcurrent_storeDuringEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit), Options{
+		Concurrent: true,
+	})
+	store := NewStore(defs)
+	household := defs.MustNewWorksheet("household")
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", NewNumberFromInt(0))
+	household.MustAppend("members", member)
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		_, err := store.Open(tx).Save(household)
+		return err
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 20; i++ {
+			member.MustSet("income", NewNumberFromInt(i))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		s.MustRunTransaction(func(tx *runner.Tx) error {
+			_, err := store.Open(tx).Update(household)
+			return err
+		})
+	}
+	wg.Wait()
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		_, err := store.Open(tx).Update(household)
+		return err
+	})
+
+	var fromStore *Worksheet
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		var err error
+		fromStore, err = store.Open(tx).Load(household.Id())
+		return err
+	})
+	require.Equal(s.T(), "20", fromStore.MustGet("total_income").String())
+}
@@ -13,6 +13,7 @@
 package worksheets
 
 import (
+	"errors"
 	"math"
 	"strings"
 
@@ -38,6 +39,48 @@ func (s *Zuite) TestWorksheet_constrainedBy() {
 	require.Equal(s.T(), `"Alex"`, ws.MustGet("name").String())
 }
 
+func (s *Zuite) TestWorksheet_constrainedByMessage() {
+	defs, err := NewDefinitions(strings.NewReader(`type applicant worksheet {
+		1:ssn  text constrained_by {
+			return len(ssn) == 9
+		} message "SSN must be 9 digits, {ssn} has {length}"
+		2:age  number[0] constrained_by { return 0 <= age } message "{age} is not a valid age"
+		3:length number[0] computed_by { return len(ssn) }
+	}`))
+	require.NoError(s.T(), err)
+
+	ws := defs.MustNewWorksheet("applicant")
+
+	err = ws.Set("ssn", NewText("123"))
+	require.EqualError(s.T(), err, "SSN must be 9 digits, 123 has 3")
+	require.False(s.T(), ws.MustIsSet("ssn"))
+
+	var violation *ConstraintViolation
+	require.True(s.T(), errors.As(err, &violation))
+	require.Equal(s.T(), "ssn", violation.Field)
+	require.Equal(s.T(), NewText("123"), violation.Value)
+
+	err = ws.Set("age", NewNumberFromInt(-3))
+	require.EqualError(s.T(), err, "-3 is not a valid age")
+
+	require.NoError(s.T(), ws.Set("ssn", NewText("123456789")))
+}
+
+func (s *Zuite) TestWorksheet_constrainedByMessageErrors() {
+	cases := map[string]string{
+		`type simple worksheet {
+			1:age number[0] constrained_by { return 0 <= age } message "{agee} is not valid"
+		}`: "simple.age message references unknown field agee",
+		`type simple worksheet {
+			1:age number[0] constrained_by { return 0 <= age } message 5
+		}`: "expected text, found 5",
+	}
+	for src, expected := range cases {
+		_, err := NewDefinitions(strings.NewReader(src))
+		require.EqualError(s.T(), err, expected, src)
+	}
+}
+
 func (s *Zuite) TestWorksheet_constrainedByNonBoolExpression() {
 	defs, err := NewDefinitions(strings.NewReader(`type constrained_non_bool_constrained_expression worksheet {
 			69:some_field number[0] constrained_by { return some_field + 2 }
@@ -0,0 +1,207 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// checkConstraints verifies that the worksheets modified by the transaction,
+// and their ancestors, satisfy the constraints of their definitions.
+func (tx *editTx) checkConstraints() error {
+	var (
+		seen = make(map[*Worksheet]bool)
+		wss  []*Worksheet
+	)
+	add := func(ws *Worksheet) {
+		if !seen[ws] {
+			seen[ws] = true
+			wss = append(wss, ws)
+		}
+	}
+	for _, ws := range tx.order {
+		if _, ok := tx.saved[ws]; !ok {
+			continue
+		}
+		add(ws)
+		ancestors := ws.ancestors()
+		var names []string
+		for name := range ancestors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, ancestor := range ancestors[name] {
+				add(ancestor)
+			}
+		}
+	}
+
+	for _, ws := range wss {
+		for _, constraint := range ws.def.constraints {
+			if err := ws.checkWorksheetConstraint(constraint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkWorksheetConstraint verifies that the worksheet satisfies `constraint`.
+// Constraints which are undefined, e.g. since fields they depend on are not
+// yet set, are satisfied.
+func (ws *Worksheet) checkWorksheetConstraint(constraint *constraint) error {
+	result, err := constraint.expr.compute(ws)
+	if err != nil {
+		return fmt.Errorf("%s constraint %s: %s", ws.def.name, constraint.name, err)
+	}
+	switch r := result.(type) {
+	case *Undefined:
+		return nil
+	case *Bool:
+		if r.value {
+			return nil
+		}
+		return &ConstraintViolation{
+			Worksheet:  ws,
+			Constraint: constraint.name,
+			Message:    ws.interpolate(constraint.message),
+		}
+	default:
+		return fmt.Errorf("%s constraint %s must be bool, was %s", ws.def.name, constraint.name, result.Type())
+	}
+}
+
+// placeholder matches the placeholders of constraint messages, e.g. `{ssn}`,
+// which are replaced by the value of the field named.
+var placeholder = regexp.MustCompile(`\{([A-Za-z][A-Za-z_0-9]*)\}`)
+
+// unknownPlaceholder returns the name of the first placeholder of `message`
+// which is not a field of the definition, if any.
+func (def *Definition) unknownPlaceholder(message string) (string, bool) {
+	for _, match := range placeholder.FindAllStringSubmatch(message, -1) {
+		if _, ok := def.fieldsByName[match[1]]; !ok {
+			return match[1], false
+		}
+	}
+	return "", true
+}
+
+// interpolate replaces the placeholders of `message` by the values of the
+// fields they name. Texts are interpolated without quotes, for messages to
+// read naturally, e.g. `123 is not a valid SSN`.
+func (ws *Worksheet) interpolate(message string) string {
+	return placeholder.ReplaceAllStringFunc(message, func(match string) string {
+		_, value, err := ws.get(match[1 : len(match)-1])
+		if err != nil {
+			return match
+		}
+		if text, ok := value.(*Text); ok {
+			return text.value
+		}
+		return value.String()
+	})
+}
+
+// Validate checks the worksheet, and all worksheets it points to, directly or
+// transitively, against their definitions: required fields must be set, enum
+// values must be elements of their enum, and all constraints, be it of fields,
+// or of worksheets, must be satisfied. Unlike edits, which are rejected on the
+// first violation, all violations are reported at once as `Violations`.
+//
+// Validation is useful for worksheets which did not go through edits, such as
+// worksheets loaded from the store after definitions changed. Constrained
+// fields which are not set are not checked. This differs from edits, where
+// unsetting a constrained field checks its constraint against undefined, since
+// a field never set cannot be told apart from a field which was unset.
+func (ws *Worksheet) Validate() error {
+	defer ws.rlock()()
+
+	var (
+		violations Violations
+		seen       = map[*Worksheet]bool{ws: true}
+		next       = []*Worksheet{ws}
+	)
+	for len(next) != 0 {
+		current := next[0]
+		next = next[1:]
+		violations = append(violations, current.validate()...)
+		for _, field := range sortedFieldsByIndex(current.def) {
+			for _, child := range extractChildWs(current.data[field.index]) {
+				if !seen[child] {
+					seen[child] = true
+					next = append(next, child)
+				}
+			}
+		}
+	}
+	if len(violations) != 0 {
+		return violations
+	}
+	return nil
+}
+
+// validate checks the fields, and constraints, of the worksheet alone.
+func (ws *Worksheet) validate() []error {
+	var violations []error
+	for _, field := range sortedFieldsByIndex(ws.def) {
+		value, isSet := ws.data[field.index]
+		if !isSet {
+			value = vUndefined
+		}
+
+		if field.required {
+			if slice, ok := value.(*Slice); !isSet || (ok && len(slice.elements) == 0) {
+				violations = append(violations, &MissingRequiredField{ws, field.name})
+			}
+		}
+
+		for _, err := range enumMismatches(field.typ, value) {
+			violations = append(violations, withField(err, ws, field))
+		}
+
+		if field.constrainedBy != nil && isSet {
+			if err := ws.checkConstraint(field, value); err != nil {
+				violations = append(violations, err)
+			}
+		}
+	}
+	for _, constraint := range ws.def.constraints {
+		if err := ws.checkWorksheetConstraint(constraint); err != nil {
+			violations = append(violations, err)
+		}
+	}
+	return violations
+}
+
+// enumMismatches checks that values of type enum in `value`, of type `typ`,
+// are elements of their enum.
+func enumMismatches(typ Type, value Value) []error {
+	switch t := typ.(type) {
+	case *EnumType:
+		if err := canAssignTo("assign", value, t); err != nil {
+			return []error{err}
+		}
+	case *SliceType:
+		if slice, ok := value.(*Slice); ok {
+			var errs []error
+			for _, element := range slice.elements {
+				errs = append(errs, enumMismatches(t.elementType, element.value)...)
+			}
+			return errs
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defsForConstraints = `
+type loan worksheet {
+	1:price        number[2]
+	2:down_payment number[2]
+	3:borrowers    []borrower
+	4:financed     number[2] computed_by {
+		return price - down_payment
+	}
+
+	constraints {
+		down_payment_within_price: down_payment <= price
+		positive_incomes:          min(borrowers.income) > 0
+		at_most_two_borrowers:     len(borrowers) <= 2
+	}
+}
+
+type borrower worksheet {
+	1:income number[0]
+}`
+
+func (s *Zuite) TestConstraints() {
+	defs := MustNewDefinitions(strings.NewReader(defsForConstraints))
+	ws := defs.MustNewWorksheet("loan")
+
+	// undefined constraints are satisfied
+	ws.MustSet("down_payment", MustNewValue("50000"))
+	ws.MustSet("price", MustNewValue("400000"))
+
+	// violations reject the whole edit
+	err := NewEdit().
+		Set("price", MustNewValue("300000")).
+		Set("down_payment", MustNewValue("350000")).
+		Apply(ws)
+	require.EqualError(s.T(), err, "loan violates constraint down_payment_within_price")
+
+	var violation *ConstraintViolation
+	require.True(s.T(), errors.As(err, &violation))
+	require.Equal(s.T(), ws, violation.Worksheet)
+	require.Equal(s.T(), "down_payment_within_price", violation.Constraint)
+
+	require.Equal(s.T(), MustNewValue("400000"), ws.MustGet("price"))
+	require.Equal(s.T(), MustNewValue("50000"), ws.MustGet("down_payment"))
+	require.Equal(s.T(), MustNewValue("350000"), ws.MustGet("financed"))
+
+	// either side of the constraint may cause the violation
+	err = ws.Set("price", MustNewValue("40000"))
+	require.EqualError(s.T(), err, "loan violates constraint down_payment_within_price")
+	err = ws.Set("down_payment", MustNewValue("400000.01"))
+	require.EqualError(s.T(), err, "loan violates constraint down_payment_within_price")
+
+	// edits resolving the violation together are accepted
+	err = NewEdit().
+		Set("price", MustNewValue("300000")).
+		Set("down_payment", MustNewValue("30000")).
+		Apply(ws)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), MustNewValue("270000"), ws.MustGet("financed"))
+}
+
+func (s *Zuite) TestConstraints_throughChildren() {
+	defs := MustNewDefinitions(strings.NewReader(defsForConstraints))
+	ws := defs.MustNewWorksheet("loan")
+
+	borrower := defs.MustNewWorksheet("borrower")
+	borrower.MustSet("income", MustNewValue("1000"))
+	ws.MustAppend("borrowers", borrower)
+
+	// undefined incomes are not yet checked
+	other := defs.MustNewWorksheet("borrower")
+	ws.MustAppend("borrowers", other)
+	borrower.MustSet("income", MustNewValue("-5"))
+	borrower.MustSet("income", MustNewValue("1000"))
+	other.MustSet("income", MustNewValue("2000"))
+
+	err := ws.Append("borrowers", defs.MustNewWorksheet("borrower"))
+	require.EqualError(s.T(), err, "loan violates constraint at_most_two_borrowers")
+	require.Len(s.T(), ws.MustGetSlice("borrowers"), 2)
+
+	// editing a child checks the constraints of its parents
+	err = borrower.Set("income", MustNewValue("-5"))
+	require.EqualError(s.T(), err, "loan violates constraint positive_incomes")
+	require.Equal(s.T(), MustNewValue("1000"), borrower.MustGet("income"))
+}
+
+func (s *Zuite) TestConstraints_editorsResolveViolations() {
+	defs := MustNewDefinitions(strings.NewReader(defsForConstraints), Options{
+		Editors: map[string][]Editor{
+			"loan": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					if proposed.IsSetting("price") && !proposed.IsSetting("down_payment") {
+						return proposed.Set("down_payment", current.MustGet("price")), nil
+					}
+					return proposed, nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("loan")
+	ws.MustSet("down_payment", MustNewValue("50000"))
+
+	// the violation of the tentative edit is resolved by the editor
+	ws.MustSet("price", MustNewValue("40000"))
+	require.Equal(s.T(), MustNewValue("40000"), ws.MustGet("down_payment"))
+}
+
+func (s *Zuite) TestConstraints_message() {
+	defs := MustNewDefinitions(strings.NewReader(`type loan worksheet {
+		1:price        number[2]
+		2:down_payment number[2]
+		constraints {
+			down_payment_within_price: down_payment <= price
+				message "down payment of {down_payment} exceeds the price of {price}"
+		}
+	}`))
+	ws := defs.MustNewWorksheet("loan")
+	ws.MustSet("price", MustNewValue("1000"))
+
+	err := ws.Set("down_payment", MustNewValue("1500"))
+	require.EqualError(s.T(), err, "down payment of 1500 exceeds the price of 1000")
+
+	var violation *ConstraintViolation
+	require.True(s.T(), errors.As(err, &violation))
+	require.Equal(s.T(), "down_payment_within_price", violation.Constraint)
+}
+
+func (s *Zuite) TestConstraints_nonBool() {
+	defs := MustNewDefinitions(strings.NewReader(`type simple worksheet {
+		1:age number[0]
+		constraints {
+			adult: age + 18
+		}
+	}`))
+	ws := defs.MustNewWorksheet("simple")
+
+	err := ws.Set("age", MustNewValue("3"))
+	require.EqualError(s.T(), err, "simple constraint adult must be bool, was number[0]")
+	require.False(s.T(), ws.MustIsSet("age"))
+}
+
+func (s *Zuite) TestConstraints_definitionErrors() {
+	cases := map[string]string{
+		`type simple worksheet {
+			constraints {
+				always: true
+			}
+		}`: "simple constraint always has no dependencies",
+		`type simple worksheet {
+			1:age number[0]
+			constraints {
+				adult: agee >= 18
+			}
+		}`: "simple constraint adult references unknown arg agee",
+		`type simple worksheet {
+			1:age number[0]
+			constraints {
+				adult: age >= 18
+			}
+			constraints {
+				adult: age > 17
+			}
+		}`: "simple: constraint adult cannot be reused",
+		`type simple worksheet {
+			1:age number[0]
+			constraints {
+				adult age >= 18
+			}
+		}`: "expected :, found age",
+		`type simple worksheet {
+			1:age number[0]
+			constraints {
+				adult: age >= 18 message "{years} is too young"
+			}
+		}`: "simple constraint adult message references unknown field years",
+	}
+	for src, expected := range cases {
+		_, err := NewDefinitions(strings.NewReader(src))
+		require.EqualError(s.T(), err, expected, src)
+	}
+}
+
+var defsForValidate = `
+type application worksheet {
+	1:applicants   []applicant required
+	2:price        number[2]
+	3:down_payment number[2]
+
+	constraints {
+		down_payment_within_price: down_payment <= price
+	}
+}
+
+type applicant worksheet {
+	1:name  text required
+	2:ssn   text constrained_by {
+		return len(ssn) == 9
+	} message "SSN must be 9 digits, {ssn} is not"
+	3:kind  kind
+	4:kinds []kind
+}
+
+type kind enum {
+	"primary",
+	"co",
+}`
+
+func (s *Zuite) TestValidate() {
+	defs := MustNewDefinitions(strings.NewReader(defsForValidate))
+	app := defs.MustNewWorksheet("application")
+	require.True(s.T(), app.def.FieldByName("applicants").IsRequired())
+
+	err := app.Validate()
+	require.EqualError(s.T(), err, "missing required field applicants")
+
+	var missing *MissingRequiredField
+	require.True(s.T(), errors.As(err, &missing))
+	require.Equal(s.T(), &MissingRequiredField{app, "applicants"}, missing)
+
+	alice := defs.MustNewWorksheet("applicant")
+	alice.MustSet("name", NewText("Alice"))
+	alice.MustSet("ssn", NewText("123456789"))
+	alice.MustSet("kind", NewText("primary"))
+	app.MustAppend("applicants", alice)
+	require.NoError(s.T(), app.Validate())
+
+	bob := defs.MustNewWorksheet("applicant")
+	app.MustAppend("applicants", bob)
+
+	// Worksheets which did not go through edits, e.g. as if loaded from the
+	// store after definitions changed, may violate their definitions.
+	set := func(ws *Worksheet, name string, value Value) {
+		ws.data[ws.def.FieldByName(name).index] = value
+	}
+	set(app, "price", MustNewValue("1000"))
+	set(app, "down_payment", MustNewValue("2000"))
+	set(alice, "ssn", NewText("123"))
+	set(alice, "kind", NewText("third"))
+	set(alice, "kinds", &Slice{
+		typ: alice.def.FieldByName("kinds").typ.(*SliceType),
+		elements: []sliceElement{
+			{1, NewText("co")},
+			{2, NewText("fourth")},
+		},
+	})
+	err = app.Validate()
+	require.EqualError(s.T(), err, strings.Join([]string{
+		"application violates constraint down_payment_within_price",
+		"SSN must be 9 digits, 123 is not",
+		"cannot assign third to kind",
+		"cannot assign fourth to kind",
+		"missing required field name",
+	}, "; "))
+
+	var violations Violations
+	require.True(s.T(), errors.As(err, &violations))
+	require.Len(s.T(), violations, 5)
+
+	var violation *ConstraintViolation
+	require.True(s.T(), errors.As(violations[1], &violation))
+	require.Equal(s.T(), alice, violation.Worksheet)
+	require.Equal(s.T(), "ssn", violation.Field)
+
+	var mismatch *TypeMismatch
+	require.True(s.T(), errors.As(violations[3], &mismatch))
+	require.Equal(s.T(), alice, mismatch.Worksheet)
+	require.Equal(s.T(), "kinds", mismatch.Field)
+	require.Equal(s.T(), NewText("fourth"), mismatch.Value)
+
+	require.True(s.T(), errors.As(violations[4], &missing))
+	require.Equal(s.T(), &MissingRequiredField{bob, "name"}, missing)
+}
+
+func (s *Zuite) TestValidate_unsetConstrainedFields() {
+	defs := MustNewDefinitions(strings.NewReader(defsForValidate))
+	alice := defs.MustNewWorksheet("applicant")
+	alice.MustSet("name", NewText("Alice"))
+	alice.MustSet("ssn", NewText("123456789"))
+
+	// Unsetting checks the constraint against undefined, which fails here.
+	err := alice.Unset("ssn")
+	require.EqualError(s.T(), err, "SSN must be 9 digits, undefined is not")
+	require.True(s.T(), alice.MustIsSet("ssn"))
+
+	// Whereas validating skips constrained fields which are not set.
+	fresh := defs.MustNewWorksheet("applicant")
+	fresh.MustSet("name", NewText("Bob"))
+	require.False(s.T(), fresh.MustIsSet("ssn"))
+	require.NoError(s.T(), fresh.Validate())
+}
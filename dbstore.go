@@ -189,6 +189,8 @@ func (s *Session) LoadContext(ctx context.Context, id string) (*Worksheet, error
 }
 
 func (s *Session) loadCommon(ctx context.Context, id string) (*Worksheet, error) {
+	// Loaded worksheets are new, and only reachable once returned, hence
+	// there is no graph to lock.
 	loader := &loader{
 		s:               s,
 		graph:           make(map[string]*Worksheet),
@@ -215,6 +217,10 @@ func (s *Session) SaveOrUpdateContext(ctx context.Context, ws *Worksheet) (strin
 }
 
 func (s *Session) saveOrUpdateCommon(ctx context.Context, ws *Worksheet) (string, error) {
+	// Persisting cascades to all worksheets linked to ws, which are part of
+	// its graph, and updates their version.
+	defer ws.lock()()
+
 	p := s.newPersister()
 	if err := p.saveOrUpdate(ctx, ws); err != nil {
 		return "", err
@@ -231,6 +237,10 @@ func (s *Session) SaveContext(ctx context.Context, ws *Worksheet) (string, error
 }
 
 func (s *Session) saveCommon(ctx context.Context, ws *Worksheet) (string, error) {
+	// Persisting cascades to all worksheets linked to ws, which are part of
+	// its graph, and updates their version.
+	defer ws.lock()()
+
 	p := s.newPersister()
 	if err := p.save(ctx, ws); err != nil {
 		return "", err
@@ -247,6 +257,10 @@ func (s *Session) UpdateContext(ctx context.Context, ws *Worksheet) (string, err
 }
 
 func (s *Session) updateCommon(ctx context.Context, ws *Worksheet) (string, error) {
+	// Persisting cascades to all worksheets linked to ws, which are part of
+	// its graph, and updates their version.
+	defer ws.lock()()
+
 	p := s.newPersister()
 	if err := p.update(ctx, ws); err != nil {
 		return "", err
@@ -263,6 +277,10 @@ type loader struct {
 	s               *Session
 	graph           map[string]*Worksheet
 	slicesToHydrate map[string]slicepair
+
+	// sharedGraph is the graph lock shared by all worksheets loaded, when
+	// concurrency safe.
+	sharedGraph *graphLock
 }
 
 func (l *loader) loadWorksheet(id string) (*Worksheet, error) {
@@ -294,6 +312,8 @@ func (l *loader) loadWorksheet(id string) (*Worksheet, error) {
 	// callers can rely on this even if the worksheet itself is not fully
 	// loaded.
 	ws.data[indexId] = NewText(id)
+	ws.id = id
+	shareGraph(&l.sharedGraph, ws)
 	l.graph[id] = ws
 
 	var valuesRecs []rValue
@@ -437,6 +457,11 @@ func (typ *SliceType) dbReadValue(l *loader, value string) (Value, Value, error)
 	}
 	sliceId := match[2]
 
+	// Nested slices are hydrated along with the slice they are in.
+	if slices, ok := l.slicesToHydrate[sliceId]; ok {
+		return slices.orig, slices.data, nil
+	}
+
 	orig := newSliceWithIdAndLastRank(typ, sliceId, lastRank)
 	data := newSliceWithIdAndLastRank(typ, sliceId, lastRank)
 	l.slicesToHydrate[sliceId] = slicepair{
@@ -545,7 +570,7 @@ func (p *persister) save(ctx context.Context, ws *Worksheet) error {
 		Columns("*").
 		Record(&rWorksheet{
 			Id:      ws.Id(),
-			Version: ws.Version(),
+			Version: ws.version(),
 			Name:    ws.Name(),
 		}).
 		ExecContext(ctx); err != nil {
@@ -560,7 +585,7 @@ func (p *persister) save(ctx context.Context, ws *Worksheet) error {
 			EditId:      p.editId,
 			CreatedAt:   p.createdAt,
 			WorksheetId: ws.Id(),
-			ToVersion:   ws.Version(),
+			ToVersion:   ws.version(),
 		}).
 		ExecContext(ctx); err != nil {
 		return err
@@ -576,13 +601,14 @@ func (p *persister) save(ctx context.Context, ws *Worksheet) error {
 		insertValues.Record(rValue{
 			WorksheetId: ws.Id(),
 			Index:       index,
-			FromVersion: ws.Version(),
+			FromVersion: ws.version(),
 			ToVersion:   math.MaxInt32,
 			Value:       dbWriteValue(value),
 		})
 
 		if slice, ok := value.(*Slice); ok {
 			slicesToInsert = append(slicesToInsert, slice)
+			slicesToInsert = append(slicesToInsert, nestedSlices(slice.elements)...)
 			for _, elem := range slice.elements {
 				for _, childWs := range extractChildWs(elem.value) {
 					adoptedChildren[index] = append(adoptedChildren[index], childWs.Id())
@@ -608,7 +634,7 @@ func (p *persister) save(ctx context.Context, ws *Worksheet) error {
 				insertSliceElements.Record(rSliceElement{
 					SliceId:     slice.id,
 					Rank:        element.rank,
-					FromVersion: ws.Version(),
+					FromVersion: ws.version(),
 					ToVersion:   math.MaxInt32,
 					Value:       dbWriteValue(element.value),
 				})
@@ -671,18 +697,18 @@ func (p *persister) update(ctx context.Context, ws *Worksheet) error {
 		}
 	}
 
-	oldVersion := ws.Version()
+	oldVersion := ws.version()
 	newVersion := oldVersion + 1
 
 	// diff
-	ws.set(ws.def.fieldsByIndex[indexVersion], &Number{int64(newVersion), &NumberType{0}})
+	ws.setAndRecompute(ws.def.fieldsByIndex[indexVersion], &Number{int64(newVersion), &NumberType{0}})
 	diff := ws.diff()
 
 	// plan rollback
 	hasFailed := true
 	defer func() {
 		if hasFailed {
-			ws.set(ws.def.fieldsByIndex[indexVersion], &Number{int64(oldVersion), &NumberType{0}})
+			ws.setAndRecompute(ws.def.fieldsByIndex[indexVersion], &Number{int64(oldVersion), &NumberType{0}})
 		}
 	}()
 
@@ -746,6 +772,26 @@ func (p *persister) update(ctx context.Context, ws *Worksheet) error {
 						}
 					}
 				}
+
+				// Elements of nested slices are stored once, when the
+				// nested slice is first added, and until it is deleted.
+				// Nested slices merely moved are left as is.
+				var (
+					nestedBefore  = nestedSliceIds(sliceBefore.elements)
+					nestedDeleted = nestedSlices(sliceChange.deleted)
+					nestedAdded   = nestedSlices(sliceChange.added)
+					addedIds      = nestedSliceIds(sliceChange.added)
+				)
+				for _, nested := range nestedDeleted {
+					if !addedIds[nested.id] && len(nested.elements) != 0 {
+						slicesElementsDeleted[nested.id] = nested.elements
+					}
+				}
+				for _, nested := range nestedAdded {
+					if !nestedBefore[nested.id] && len(nested.elements) != 0 {
+						slicesElementsAdded[nested.id] = nested.elements
+					}
+				}
 			} else {
 				panic("unexpected: changing slice id not supported yet")
 			}
@@ -783,7 +829,7 @@ func (p *persister) update(ctx context.Context, ws *Worksheet) error {
 		}).
 		ExecContext(ctx)
 	if isSpecificUniqueConstraintErr(err, "worksheet_edits_worksheet_id_to_version_key") {
-		return fmt.Errorf("concurrent update detected (%s)", err)
+		return &ConcurrentModification{ws, err}
 	} else if err != nil {
 		return err
 	}
@@ -883,7 +929,7 @@ func (p *persister) update(ctx context.Context, ws *Worksheet) error {
 		ExecContext(ctx); err != nil {
 		return err
 	} else if result.RowsAffected != 1 {
-		return fmt.Errorf("concurrent update detected")
+		return &ConcurrentModification{Worksheet: ws}
 	}
 
 	// now we can update ws itself to reflect the store
@@ -895,6 +941,27 @@ func (p *persister) update(ctx context.Context, ws *Worksheet) error {
 	return nil
 }
 
+// nestedSlices returns the slices nested in `elements`, at any depth.
+func nestedSlices(elements []sliceElement) []*Slice {
+	var slices []*Slice
+	for _, element := range elements {
+		if slice, ok := element.value.(*Slice); ok {
+			slices = append(slices, slice)
+			slices = append(slices, nestedSlices(slice.elements)...)
+		}
+	}
+	return slices
+}
+
+// nestedSliceIds returns the ids of the slices nested in `elements`.
+func nestedSliceIds(elements []sliceElement) map[string]bool {
+	ids := make(map[string]bool)
+	for _, slice := range nestedSlices(elements) {
+		ids[slice.id] = true
+	}
+	return ids
+}
+
 func toOrig(value Value) Value {
 	// TODO(pascal): We need to recursively convert, e.g. handle slices. Not
 	// doing this today simplifies the persistence code, at the cost of missing
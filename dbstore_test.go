@@ -14,6 +14,7 @@ package worksheets
 
 import (
 	"context"
+	"errors"
 	"math"
 	"strings"
 	"time"
@@ -629,6 +630,10 @@ func (s *Zuite) TestUpdateDetectsConcurrentModifications_onWorksheetVersion() {
 	})
 
 	require.EqualError(s.T(), errFromUpdate, "concurrent update detected")
+
+	var modification *ConcurrentModification
+	require.True(s.T(), errors.As(errFromUpdate, &modification))
+	require.Equal(s.T(), ws, modification.Worksheet)
 }
 
 func (s *Zuite) TestUpdateDetectsConcurrentModifications_onEditRecordAlreadyPresent() {
@@ -661,6 +666,10 @@ func (s *Zuite) TestUpdateDetectsConcurrentModifications_onEditRecordAlreadyPres
 	})
 
 	require.Regexp(s.T(), `^concurrent update detected \(.*\)$`, errFromUpdate)
+
+	var modification *ConcurrentModification
+	require.True(s.T(), errors.As(errFromUpdate, &modification))
+	require.NotNil(s.T(), modification.Err)
 }
 
 func (s *Zuite) TestSignoffPattern() {
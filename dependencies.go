@@ -0,0 +1,199 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DependencyGraph is the graph of computed fields, and the fields they depend
+// on, across all worksheet definitions.
+type DependencyGraph struct {
+	fields []*Field
+	edges  []DependencyEdge
+}
+
+// DependencyEdge indicates that the computed field `To` depends on `From`,
+// i.e. that `To` needs to be recomputed whenever `From` changes.
+//
+// Dependencies through selectors such as `a.b.c` yield edges from every field
+// along the path, e.g. `a`, `b`, and `c`, since changing any of them changes
+// the selected value.
+type DependencyEdge struct {
+	From, To *Field
+}
+
+// DependencyGraph returns the dependency graph of all computed fields.
+//
+// All fields are part of the graph, except for the `id` and `version` fields
+// which are only included when depended on.
+func (defs *Definitions) DependencyGraph() *DependencyGraph {
+	g := &DependencyGraph{}
+
+	inEdge := make(map[*Field]bool)
+	for _, typ := range defs.Types() {
+		def, ok := typ.(*Definition)
+		if !ok {
+			continue
+		}
+		for _, field := range sortedFieldsByIndex(def) {
+			for _, dependent := range field.Dependents() {
+				g.edges = append(g.edges, DependencyEdge{field, dependent})
+				inEdge[field] = true
+				inEdge[dependent] = true
+			}
+		}
+	}
+
+	for _, typ := range defs.Types() {
+		def, ok := typ.(*Definition)
+		if !ok {
+			continue
+		}
+		for _, field := range sortedFieldsByIndex(def) {
+			if 0 < field.index || inEdge[field] {
+				g.fields = append(g.fields, field)
+			}
+		}
+	}
+
+	position := make(map[*Field]int)
+	for i, field := range g.fields {
+		position[field] = i
+	}
+	sort.SliceStable(g.edges, func(i, j int) bool {
+		ei, ej := g.edges[i], g.edges[j]
+		if ei.From != ej.From {
+			return position[ei.From] < position[ej.From]
+		}
+		return position[ei.To] < position[ej.To]
+	})
+
+	return g
+}
+
+// Fields returns all fields in the graph, ordered by worksheet name, and then
+// by field index.
+func (g *DependencyGraph) Fields() []*Field {
+	return g.fields
+}
+
+// Edges returns all dependencies in the graph.
+func (g *DependencyGraph) Edges() []DependencyEdge {
+	return g.edges
+}
+
+// DOT renders the graph in the Graphviz DOT language. Fields are grouped by
+// worksheet, computed fields are drawn as boxes, and fields computed by
+// plugins are filled.
+func (g *DependencyGraph) DOT() string {
+	var b bytes.Buffer
+	b.WriteString("digraph worksheets {\n")
+	b.WriteString("\trankdir=LR;\n")
+	g.forEachDefinition(func(def *Definition, fields []*Field) {
+		fmt.Fprintf(&b, "\tsubgraph %s {\n", strconv.Quote("cluster_"+def.name))
+		fmt.Fprintf(&b, "\t\tlabel=%s;\n", strconv.Quote(def.name))
+		for _, field := range fields {
+			attrs := fmt.Sprintf("label=%s", strconv.Quote(field.name))
+			if field.computedBy != nil {
+				attrs += ", shape=box"
+			}
+			if field.isComputedByPlugin() {
+				attrs += ", style=filled, fillcolor=lightgrey"
+			}
+			fmt.Fprintf(&b, "\t\t%s [%s];\n", strconv.Quote(field.qualifiedName()), attrs)
+		}
+		b.WriteString("\t}\n")
+	})
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(edge.From.qualifiedName()), strconv.Quote(edge.To.qualifiedName()))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Fields are grouped by
+// worksheet, computed fields are drawn with rounded edges, and fields computed
+// by plugins are highlighted with the `external` class.
+func (g *DependencyGraph) Mermaid() string {
+	// Mermaid identifiers are restricted, so we number nodes rather than
+	// rely on names.
+	ids := make(map[*Field]string)
+	for i, field := range g.fields {
+		ids[field] = fmt.Sprintf("n%d", i)
+	}
+
+	var (
+		b         bytes.Buffer
+		externals []string
+	)
+	b.WriteString("flowchart LR\n")
+	g.forEachDefinition(func(def *Definition, fields []*Field) {
+		fmt.Fprintf(&b, "\tsubgraph %s\n", def.name)
+		for _, field := range fields {
+			if field.computedBy != nil {
+				fmt.Fprintf(&b, "\t\t%s(%s)\n", ids[field], strconv.Quote(field.name))
+			} else {
+				fmt.Fprintf(&b, "\t\t%s[%s]\n", ids[field], strconv.Quote(field.name))
+			}
+			if field.isComputedByPlugin() {
+				externals = append(externals, ids[field])
+			}
+		}
+		b.WriteString("\tend\n")
+	})
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "\t%s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+	if len(externals) != 0 {
+		b.WriteString("\tclassDef external fill:#ddd,stroke-dasharray:5 5\n")
+		for _, id := range externals {
+			fmt.Fprintf(&b, "\tclass %s external\n", id)
+		}
+	}
+	return b.String()
+}
+
+// forEachDefinition iterates over the fields of the graph grouped by the
+// definition they belong to.
+func (g *DependencyGraph) forEachDefinition(fn func(def *Definition, fields []*Field)) {
+	for i := 0; i < len(g.fields); {
+		def := g.fields[i].def
+		j := i
+		for j < len(g.fields) && g.fields[j].def == def {
+			j++
+		}
+		fn(def, g.fields[i:j])
+		i = j
+	}
+}
+
+func sortedFieldsByIndex(def *Definition) []*Field {
+	fields := def.Fields()
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].index < fields[j].index
+	})
+	return fields
+}
+
+func (f *Field) qualifiedName() string {
+	return fmt.Sprintf("%s.%s", f.def.name, f.name)
+}
+
+func (f *Field) isComputedByPlugin() bool {
+	_, ok := f.computedBy.(*ePlugin)
+	return ok
+}
@@ -0,0 +1,185 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defsForDependencies = `
+type game worksheet {
+	1:board   board
+	2:winner  text computed_by {
+		return if(board.row.full, board.row.owner)
+	}
+	3:summary text computed_by { external }
+}
+
+type board worksheet {
+	1:row row
+	2:moves number[0]
+}
+
+type row worksheet {
+	1:owner text
+	2:full  bool
+}`
+
+func (s *Zuite) defsForDependencies() *Definitions {
+	return MustNewDefinitions(strings.NewReader(defsForDependencies), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"game": {
+				"summary": sayAlice([]string{"winner", "board.moves"}),
+			},
+		},
+	})
+}
+
+func (s *Zuite) TestDependencyGraph() {
+	defs := s.defsForDependencies()
+
+	g := defs.DependencyGraph()
+
+	var fields []string
+	for _, field := range g.Fields() {
+		fields = append(fields, field.qualifiedName())
+	}
+	require.Equal(s.T(), []string{
+		"board.row",
+		"board.moves",
+		"game.board",
+		"game.winner",
+		"game.summary",
+		"row.owner",
+		"row.full",
+	}, fields)
+
+	var edges []string
+	for _, edge := range g.Edges() {
+		edges = append(edges, edge.From.qualifiedName()+" -> "+edge.To.qualifiedName())
+	}
+	require.Equal(s.T(), []string{
+		"board.row -> game.winner",
+		"board.moves -> game.summary",
+		"game.board -> game.winner",
+		"game.board -> game.summary",
+		"game.winner -> game.summary",
+		"row.owner -> game.winner",
+		"row.full -> game.winner",
+	}, edges)
+}
+
+func (s *Zuite) TestDependencyGraph_DOT() {
+	defs := s.defsForDependencies()
+
+	expected := `digraph worksheets {
+	rankdir=LR;
+	subgraph "cluster_board" {
+		label="board";
+		"board.row" [label="row"];
+		"board.moves" [label="moves"];
+	}
+	subgraph "cluster_game" {
+		label="game";
+		"game.board" [label="board"];
+		"game.winner" [label="winner", shape=box];
+		"game.summary" [label="summary", shape=box, style=filled, fillcolor=lightgrey];
+	}
+	subgraph "cluster_row" {
+		label="row";
+		"row.owner" [label="owner"];
+		"row.full" [label="full"];
+	}
+	"board.row" -> "game.winner";
+	"board.moves" -> "game.summary";
+	"game.board" -> "game.winner";
+	"game.board" -> "game.summary";
+	"game.winner" -> "game.summary";
+	"row.owner" -> "game.winner";
+	"row.full" -> "game.winner";
+}
+`
+	require.Equal(s.T(), expected, defs.DependencyGraph().DOT())
+}
+
+func (s *Zuite) TestDependencyGraph_Mermaid() {
+	defs := s.defsForDependencies()
+
+	expected := `flowchart LR
+	subgraph board
+		n0["row"]
+		n1["moves"]
+	end
+	subgraph game
+		n2["board"]
+		n3("winner")
+		n4("summary")
+	end
+	subgraph row
+		n5["owner"]
+		n6["full"]
+	end
+	n0 --> n3
+	n1 --> n4
+	n2 --> n3
+	n2 --> n4
+	n3 --> n4
+	n5 --> n3
+	n6 --> n3
+	classDef external fill:#ddd,stroke-dasharray:5 5
+	class n4 external
+`
+	require.Equal(s.T(), expected, defs.DependencyGraph().Mermaid())
+}
+
+func (s *Zuite) TestDependencyGraph_versionIncludedOnlyWhenDependedOn() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type sign_off worksheet {
+		1:requires_review    requires_review
+		2:signed_off_version number[0]
+		3:signed_off         bool computed_by {
+			return requires_review.version == signed_off_version
+		}
+	}
+
+	type requires_review worksheet {
+		1:data text
+	}`))
+
+	var fields []string
+	for _, field := range defs.DependencyGraph().Fields() {
+		fields = append(fields, field.qualifiedName())
+	}
+	require.Equal(s.T(), []string{
+		"requires_review.version",
+		"requires_review.data",
+		"sign_off.requires_review",
+		"sign_off.signed_off_version",
+		"sign_off.signed_off",
+	}, fields)
+}
+
+func (s *Zuite) TestField_Dependents() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type simple worksheet {
+		1:age    number[0]
+		2:double number[0] computed_by { return age + age }
+	}`))
+	def := defs.defs["simple"].(*Definition)
+
+	dependents := def.FieldByName("age").Dependents()
+	require.Equal(s.T(), []*Field{def.FieldByName("double")}, dependents)
+	require.Equal(s.T(), def, def.FieldByName("age").Definition())
+}
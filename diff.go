@@ -41,13 +41,28 @@ func (value *Slice) diffCompare(that Value) bool {
 	// situations.
 	//
 	// See also toOrig.
-	return value.Equal(that)
+	if value.Equal(that) {
+		return true
+	}
+
+	// Nested slices are loaded twice, once as orig, and once as data, and
+	// are the same slice when they have the same id, and the same elements.
+	other, ok := that.(*Slice)
+	if !ok || value.id != other.id || value.lastRank != other.lastRank || len(value.elements) != len(other.elements) {
+		return false
+	}
+	for i, element := range value.elements {
+		if element.rank != other.elements[i].rank || !element.value.diffCompare(other.elements[i].value) {
+			return false
+		}
+	}
+	return true
 }
 
 func (ws *Worksheet) diffCompare(other Value) bool {
 	switch that := other.(type) {
 	case *wsRefAtVersion:
-		return ws.Version() == that.version && ws.Equal(that.ws)
+		return ws.version() == that.version && ws.Equal(that.ws)
 	case *Worksheet:
 		return ws == that
 	default:
@@ -60,7 +75,7 @@ func (value *wsRefAtVersion) diffCompare(other Value) bool {
 	case *wsRefAtVersion:
 		return value.version == that.version && value.ws.Equal(that.ws)
 	case *Worksheet:
-		return value.version == that.Version() && value.ws.Equal(that)
+		return value.version == that.version() && value.ws.Equal(that)
 	default:
 		return false
 	}
@@ -0,0 +1,721 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Edit is an edit block, i.e. a group of individual edits to be applied
+// atomically to a worksheet: either all individual edits succeed, or none do.
+//
+// Edits are proposed by calling `Set`, `Unset`, `Append`, `Del`, `Insert`,
+// `SetAt`, `Move`, or `Clear`, and are only checked when the edit is applied.
+type Edit struct {
+	ops []editOp
+}
+
+type editOpKind string
+
+const (
+	editSet    editOpKind = "set"
+	editUnset  editOpKind = "unset"
+	editAppend editOpKind = "append"
+	editDel    editOpKind = "del"
+	editInsert editOpKind = "insert"
+	editSetAt  editOpKind = "setAt"
+	editMove   editOpKind = "move"
+	editClear  editOpKind = "clear"
+
+	// editRestore restores a slice field to a prior state, and is only used
+	// to undo, and redo edits.
+	editRestore editOpKind = "restore"
+)
+
+type editOp struct {
+	kind  editOpKind
+	name  string
+	value Value
+	index int
+	to    int
+}
+
+// NewEdit creates an empty edit block.
+func NewEdit() *Edit {
+	return &Edit{}
+}
+
+// Set proposes to set the field `name` to `value`.
+func (e *Edit) Set(name string, value Value) *Edit {
+	e.ops = append(e.ops, editOp{kind: editSet, name: name, value: value})
+	return e
+}
+
+// Unset proposes to unset the field `name`.
+func (e *Edit) Unset(name string) *Edit {
+	e.ops = append(e.ops, editOp{kind: editUnset, name: name})
+	return e
+}
+
+// Append proposes to append `element` to the slice field `name`.
+func (e *Edit) Append(name string, element Value) *Edit {
+	e.ops = append(e.ops, editOp{kind: editAppend, name: name, value: element})
+	return e
+}
+
+// Del proposes to delete the element at `index` of the slice field `name`.
+func (e *Edit) Del(name string, index int) *Edit {
+	e.ops = append(e.ops, editOp{kind: editDel, name: name, index: index})
+	return e
+}
+
+// Insert proposes to insert `element` before the element at `index` of the
+// slice field `name`.
+func (e *Edit) Insert(name string, index int, element Value) *Edit {
+	e.ops = append(e.ops, editOp{kind: editInsert, name: name, value: element, index: index})
+	return e
+}
+
+// SetAt proposes to replace the element at `index` of the slice field `name`.
+func (e *Edit) SetAt(name string, index int, element Value) *Edit {
+	e.ops = append(e.ops, editOp{kind: editSetAt, name: name, value: element, index: index})
+	return e
+}
+
+// Move proposes to move the element at `from` of the slice field `name`, such
+// that it is at `to` once moved.
+func (e *Edit) Move(name string, from, to int) *Edit {
+	e.ops = append(e.ops, editOp{kind: editMove, name: name, index: from, to: to})
+	return e
+}
+
+// Clear proposes to remove all elements of the slice field `name`.
+func (e *Edit) Clear(name string) *Edit {
+	e.ops = append(e.ops, editOp{kind: editClear, name: name})
+	return e
+}
+
+// Edit builds an edit block with `fn`, and applies it to this worksheet. If
+// `fn` returns an error, the edit is abandoned, and nothing is applied.
+func (ws *Worksheet) Edit(fn func(e *Edit) error) error {
+	e := NewEdit()
+	if err := fn(e); err != nil {
+		return err
+	}
+	return e.Apply(ws)
+}
+
+// Editor intercepts edits of worksheets, and is able to react to them by
+// proposing further edits. Editors are registered via `Options.Editors`.
+type Editor interface {
+	// OnEdit is invoked with the worksheet as it would be, were the
+	// proposed edit to be applied, and returns the edit to apply instead.
+	// Returning the proposed edit unchanged accepts it, while returning an
+	// error rejects it.
+	OnEdit(current *Worksheet, proposed *Edit) (*Edit, error)
+}
+
+// maxEditIterations bounds the number of rounds of editors reacting to each
+// other's edits, as a last resort against editors which never settle.
+const maxEditIterations = 100
+
+// IsSetting indicates whether the edit sets, or unsets, the field `name`.
+func (e *Edit) IsSetting(name string) bool {
+	for _, op := range e.ops {
+		if op.name == name && (op.kind == editSet || op.kind == editUnset) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEditing indicates whether the edit modifies the field `name` in any way,
+// be it by setting, unsetting, appending to, or deleting from it.
+func (e *Edit) IsEditing(name string) bool {
+	for _, op := range e.ops {
+		if op.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Edit) clone() *Edit {
+	return &Edit{
+		ops: append([]editOp(nil), e.ops...),
+	}
+}
+
+func (e *Edit) equal(that *Edit) bool {
+	if len(e.ops) != len(that.ops) {
+		return false
+	}
+	for i, op := range e.ops {
+		other := that.ops[i]
+		if op.kind != other.kind || op.name != other.name || op.index != other.index || op.to != other.to {
+			return false
+		}
+		if (op.value == nil) != (other.value == nil) || (op.value != nil && !op.value.Equal(other.value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply applies the edit to the worksheet `ws`.
+//
+// Individual edits are applied in order, and then all computed fields affected
+// are recomputed, each exactly once. This is done tentatively: all
+// worksheets touched, be it `ws`, or parents whose computed fields are
+// recomputed, have their state copied before it is first modified. Should any
+// individual edit fail, including due to constrained fields, all worksheets
+// touched are reverted to their state prior to the edit.
+//
+// When editors are registered for the worksheet, they are then given the
+// opportunity to react to the tentative state of the worksheet by proposing a
+// different edit, which is in turn tentatively applied, and so on until
+// editors accept the edit, or the worksheets stop changing. Should editors
+// cycle between states, the edit is rejected as unstable.
+//
+// Finally, once the edit is applied, listeners are notified of all changes,
+// see Subscribe.
+func (e *Edit) Apply(ws *Worksheet) error {
+	return ws.applyEdit(e.worksheets(), func() (*Edit, error) {
+		return e, nil
+	}, func(changes []Change) {
+		ws.history.record(ws, changes)
+	})
+}
+
+// applyEdit applies the edit built by `prepare`, and then invokes `commit`
+// with the resulting changes. This is done with the graph of ws locked, along
+// with the graphs of the worksheets the edit links to, which are expected to
+// be `linked`. Listeners are notified once graphs are unlocked.
+func (ws *Worksheet) applyEdit(linked []*Worksheet, prepare func() (*Edit, error), commit func(changes []Change)) error {
+	linked = append([]*Worksheet{ws}, linked...)
+	for {
+		unlock, graph := lockGraphs(linked)
+		pending, err := ws.applyEditLocked(graph, prepare, commit)
+		unlock()
+
+		// Should the edit link to worksheets of other graphs than expected,
+		// we start over with these graphs locked too.
+		if unlocked, ok := err.(*unlockedWorksheets); ok {
+			linked = append(linked, unlocked.worksheets...)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		notify(pending)
+		return nil
+	}
+}
+
+func (ws *Worksheet) applyEditLocked(graph *graphLock, prepare func() (*Edit, error), commit func(changes []Change)) ([]notification, error) {
+	e, err := prepare()
+	if err != nil {
+		return nil, err
+	}
+	if ws.frozen {
+		return nil, fmt.Errorf("cannot edit frozen worksheet %s", ws.def.name)
+	}
+
+	tx := newEditTx()
+	tx.graph = graph
+	if err := tx.checkLocked(e); err != nil {
+		return nil, err
+	}
+	if err := e.settle(tx, ws); err != nil {
+		return nil, err
+	}
+	if err := tx.checkFrozen(); err != nil {
+		tx.rollback()
+		return nil, err
+	}
+	if err := tx.checkConstraints(); err != nil {
+		tx.rollback()
+		return nil, err
+	}
+
+	changes := tx.changes()
+	commit(changes)
+	return notificationsOf(changes), nil
+}
+
+// settle applies the edit, and the edits proposed by editors, until a fixed
+// point is reached. On failure, all changes are rolled back.
+func (e *Edit) settle(tx *editTx, ws *Worksheet) error {
+	var (
+		proposed = e
+		states   []map[*Worksheet]map[int]Value
+	)
+	for iteration := 0; ; iteration++ {
+		if err := proposed.applyTentatively(tx, ws); err != nil {
+			tx.rollback()
+			return err
+		}
+		if len(ws.def.editors) == 0 {
+			return nil
+		}
+
+		// Compare with earlier states, to determine whether we've reached
+		// a fixed point, or are cycling.
+		state := tx.state()
+		for i, previous := range states {
+			if tx.statesEqual(state, previous) {
+				if i == len(states)-1 {
+					return nil
+				}
+				tx.rollback()
+				return fmt.Errorf("unstable edit: editors cycle after %d iterations", iteration)
+			}
+		}
+		states = append(states, state)
+		if iteration == maxEditIterations {
+			tx.rollback()
+			return fmt.Errorf("unstable edit: editors did not settle after %d iterations", iteration)
+		}
+
+		next, err := runEditors(ws, proposed)
+		if err == nil {
+			err = tx.checkLocked(next)
+		}
+		if err != nil {
+			tx.rollback()
+			return err
+		}
+		if next.equal(proposed) {
+			return nil
+		}
+
+		// Apply the edit proposed by editors from scratch.
+		proposed = next
+		tx.restart()
+	}
+}
+
+// applyTentatively applies all individual edits, then recomputes all computed
+// fields affected, and finally checks constrained fields which were set.
+func (e *Edit) applyTentatively(tx *editTx, ws *Worksheet) error {
+	for _, op := range e.ops {
+		if err := op.apply(tx, ws); err != nil {
+			return err
+		}
+	}
+	if err := tx.recompute(); err != nil {
+		return err
+	}
+	for _, op := range e.ops {
+		if op.kind != editSet && op.kind != editUnset {
+			continue
+		}
+		field := ws.def.fieldsByName[op.name]
+		if field.constrainedBy == nil {
+			continue
+		}
+		value := op.value
+		if op.kind == editUnset {
+			value = vUndefined
+		}
+		if err := ws.checkConstraint(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runEditors(ws *Worksheet, proposed *Edit) (*Edit, error) {
+	// When concurrency safe, the graph is locked, and editors therefore get
+	// a snapshot which they can read without locking.
+	current := ws
+	if ws.graph != nil {
+		current = ws.snapshot(make(map[*Worksheet]*Worksheet))
+	}
+	for _, editor := range ws.def.editors {
+		// Editors get a copy of the edit, which is theirs to modify.
+		next, err := editor.OnEdit(current, proposed.clone())
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			proposed = next
+		}
+	}
+	return proposed, nil
+}
+
+func (op editOp) apply(tx *editTx, ws *Worksheet) error {
+	switch op.kind {
+	case editSet, editUnset:
+		value := op.value
+		if op.kind == editUnset {
+			if field, ok := ws.def.fieldsByName[op.name]; ok {
+				if _, ok := field.typ.(*SliceType); ok {
+					return fmt.Errorf("Unset on slice field names, must use Del")
+				}
+			}
+			value = vUndefined
+		}
+		field, err := ws.fieldForSet(op.name)
+		if err != nil {
+			return err
+		}
+		return ws.set(tx, field, value)
+
+	case editAppend:
+		field, err := ws.fieldForAppend(op.name)
+		if err != nil {
+			return err
+		}
+		return ws.append(tx, field, owned(op.value))
+
+	case editDel:
+		field, err := ws.fieldForDel(op.name)
+		if err != nil {
+			return err
+		}
+		return ws.del(tx, field, op.index)
+
+	case editInsert:
+		field, err := ws.fieldForSlice("Insert", op.name)
+		if err != nil {
+			return err
+		}
+		return ws.updateSlice(tx, field, func(slice *Slice) (*Slice, error) {
+			return slice.doInsert(op.index, owned(op.value))
+		})
+
+	case editSetAt:
+		field, err := ws.fieldForSlice("SetAt", op.name)
+		if err != nil {
+			return err
+		}
+		return ws.updateSlice(tx, field, func(slice *Slice) (*Slice, error) {
+			return slice.doSetAt(op.index, owned(op.value))
+		})
+
+	case editMove:
+		field, err := ws.fieldForSlice("Move", op.name)
+		if err != nil {
+			return err
+		}
+		return ws.updateSlice(tx, field, func(slice *Slice) (*Slice, error) {
+			return slice.doMove(op.index, op.to)
+		})
+
+	case editClear:
+		field, err := ws.fieldForSlice("Clear", op.name)
+		if err != nil {
+			return err
+		}
+		return ws.updateSlice(tx, field, func(slice *Slice) (*Slice, error) {
+			return slice.doClear(), nil
+		})
+
+	case editRestore:
+		field, err := ws.fieldForDel(op.name)
+		if err != nil {
+			return err
+		}
+		return ws.restore(tx, field, op.value.(*Slice))
+
+	default:
+		panic(fmt.Sprintf("unexpected edit %s", op.kind))
+	}
+}
+
+// editTx tracks the worksheets modified while applying an edit, and the
+// computed fields which need to be recomputed as a result.
+//
+// Before a worksheet is first modified, its data and parents are copied, and
+// the worksheet is switched to use the copies. The original data and parents
+// are therefore never modified during the edit, and rolling back amounts to
+// switching back to them.
+type editTx struct {
+	saved map[*Worksheet]wsState
+
+	// queue holds the computed fields to recompute, ordered by rank.
+	queue  recomputeQueue
+	queued map[recomputeKey]bool
+
+	// originals records the data of all worksheets touched since the start
+	// of the transaction, across restarts, and order records the order in
+	// which they were first touched.
+	originals map[*Worksheet]map[int]Value
+	order     []*Worksheet
+
+	// graph is the graph locked for the transaction, if worksheets are
+	// concurrency safe.
+	graph *graphLock
+}
+
+type wsState struct {
+	data    map[int]Value
+	parents parentsRefs
+}
+
+func newEditTx() *editTx {
+	return &editTx{
+		saved:     make(map[*Worksheet]wsState),
+		queued:    make(map[recomputeKey]bool),
+		originals: make(map[*Worksheet]map[int]Value),
+	}
+}
+
+func (tx *editTx) touch(ws *Worksheet) {
+	if _, ok := tx.saved[ws]; ok {
+		return
+	}
+	tx.saved[ws] = wsState{ws.data, ws.parents}
+	if _, ok := tx.originals[ws]; !ok {
+		tx.originals[ws] = ws.data
+		tx.order = append(tx.order, ws)
+	}
+
+	data := make(map[int]Value, len(ws.data))
+	for index, value := range ws.data {
+		data[index] = value
+	}
+	ws.data = data
+	ws.parents = ws.parents.copy()
+}
+
+func (tx *editTx) rollback() {
+	for ws, state := range tx.saved {
+		ws.data = state.data
+		ws.parents = state.parents
+	}
+	tx.saved = nil
+	tx.queue = nil
+	tx.queued = make(map[recomputeKey]bool)
+}
+
+// restart rolls back all changes, while continuing to track worksheets.
+func (tx *editTx) restart() {
+	tx.rollback()
+	tx.saved = make(map[*Worksheet]wsState)
+}
+
+// markDependents enqueues all computed fields depending on the field of ws,
+// be it on ws itself, or on its parents.
+func (tx *editTx) markDependents(ws *Worksheet, field *Field) {
+	for _, key := range ws.dependents(field) {
+		tx.enqueue(key.ws, key.field)
+	}
+}
+
+// dependents returns the computed fields, on this worksheet or its ancestors,
+// which directly depend on field.
+func (ws *Worksheet) dependents(field *Field) []recomputeKey {
+	var (
+		ancestors  map[string][]*Worksheet
+		dependents []recomputeKey
+	)
+	for _, dependentField := range field.Dependents() {
+		if dependentField.def == ws.def {
+			if field.dependentsLocal[dependentField] {
+				dependents = append(dependents, recomputeKey{ws, dependentField})
+			}
+			if !field.dependentsThroughRefs[dependentField] {
+				continue
+			}
+		}
+		// Selectors such as `borrowers.incomes.amount` go through several
+		// worksheets, and dependents may therefore be in any ancestor.
+		if ancestors == nil {
+			ancestors = ws.ancestors()
+		}
+		for _, ancestor := range ancestors[dependentField.def.name] {
+			dependents = append(dependents, recomputeKey{ancestor, dependentField})
+		}
+	}
+	return dependents
+}
+
+// ancestors returns the parents of this worksheet, their parents, and so on,
+// by worksheet name.
+func (ws *Worksheet) ancestors() map[string][]*Worksheet {
+	var (
+		ancestors = make(map[string][]*Worksheet)
+		seen      = map[*Worksheet]bool{ws: true}
+		next      = []*Worksheet{ws}
+	)
+	for len(next) != 0 {
+		current := next[0]
+		next = next[1:]
+		for name, parentsByFieldIndex := range current.parents {
+			for _, parents := range parentsByFieldIndex {
+				for _, parent := range parents {
+					if !seen[parent] {
+						seen[parent] = true
+						ancestors[name] = append(ancestors[name], parent)
+						next = append(next, parent)
+					}
+				}
+			}
+		}
+	}
+	return ancestors
+}
+
+func (tx *editTx) enqueue(ws *Worksheet, field *Field) {
+	key := recomputeKey{ws, field}
+	if !tx.queued[key] {
+		tx.queued[key] = true
+		heap.Push(&tx.queue, key)
+	}
+}
+
+// recompute recomputes all enqueued computed fields by increasing rank. Since
+// fields are ranked topologically, fields are computed after all fields they
+// depend on, and each field is computed at most once. Fields of recursive
+// definitions share their rank across worksheets, and may be recomputed again
+// once a descendant's value changes.
+func (tx *editTx) recompute() error {
+	for len(tx.queue) != 0 {
+		key := heap.Pop(&tx.queue).(recomputeKey)
+		delete(tx.queued, key)
+
+		value, err := key.field.computedBy.compute(key.ws)
+		if err != nil {
+			return err
+		}
+		if err := key.ws.set(tx, key.field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAndRecompute sets a field, and recomputes its dependents, outside of any
+// edit.
+func (ws *Worksheet) setAndRecompute(field *Field, value Value) error {
+	tx := newEditTx()
+	if err := ws.set(tx, field, value); err != nil {
+		return err
+	}
+	return tx.recompute()
+}
+
+type recomputeKey struct {
+	ws    *Worksheet
+	field *Field
+}
+
+// recomputeQueue is a priority queue of computed fields, ordered by rank, and
+// implementing heap.Interface.
+type recomputeQueue []recomputeKey
+
+func (q recomputeQueue) Len() int {
+	return len(q)
+}
+
+func (q recomputeQueue) Less(i, j int) bool {
+	if q[i].field.rank != q[j].field.rank {
+		return q[i].field.rank < q[j].field.rank
+	}
+	return q[i].ws.Id() < q[j].ws.Id()
+}
+
+func (q recomputeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *recomputeQueue) Push(x interface{}) {
+	*q = append(*q, x.(recomputeKey))
+}
+
+func (q *recomputeQueue) Pop() interface{} {
+	old := *q
+	key := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return key
+}
+
+// state captures the data of all worksheets touched.
+func (tx *editTx) state() map[*Worksheet]map[int]Value {
+	state := make(map[*Worksheet]map[int]Value, len(tx.saved))
+	for ws := range tx.saved {
+		state[ws] = ws.data
+	}
+	return state
+}
+
+// statesEqual compares two states captured during the transaction. Worksheets
+// absent from a state are in their original state.
+func (tx *editTx) statesEqual(a, b map[*Worksheet]map[int]Value) bool {
+	for ws, original := range tx.originals {
+		dataA, ok := a[ws]
+		if !ok {
+			dataA = original
+		}
+		dataB, ok := b[ws]
+		if !ok {
+			dataB = original
+		}
+		if !dataEqual(dataA, dataB) {
+			return false
+		}
+	}
+	return true
+}
+
+func dataEqual(a, b map[int]Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index, valueA := range a {
+		valueB, ok := b[index]
+		if !ok || !valuesEqual(valueA, valueB) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares values structurally. Unlike `Equal`, slices with the
+// same elements are equal, regardless of their identity.
+func valuesEqual(a, b Value) bool {
+	sliceA, ok := a.(*Slice)
+	if !ok {
+		return a.Equal(b)
+	}
+	sliceB, ok := b.(*Slice)
+	if !ok || len(sliceA.elements) != len(sliceB.elements) {
+		return false
+	}
+	for i := range sliceA.elements {
+		if !valuesEqual(sliceA.elements[i].value, sliceB.elements[i].value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (parents parentsRefs) copy() parentsRefs {
+	dup := make(parentsRefs, len(parents))
+	for parentName, byParentFieldIndex := range parents {
+		dupByParentFieldIndex := make(map[int]map[string]*Worksheet, len(byParentFieldIndex))
+		for fieldIndex, byParentId := range byParentFieldIndex {
+			dupByParentId := make(map[string]*Worksheet, len(byParentId))
+			for id, parent := range byParentId {
+				dupByParentId[id] = parent
+			}
+			dupByParentFieldIndex[fieldIndex] = dupByParentId
+		}
+		dup[parentName] = dupByParentFieldIndex
+	}
+	return dup
+}
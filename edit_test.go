@@ -0,0 +1,324 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defsForEdit = `
+type household worksheet {
+	1:members      []member
+	2:total_income number[0] computed_by {
+		return sum(members.income)
+	}
+}
+
+type member worksheet {
+	1:name    text constrained_by { return name != "Mallory" }
+	2:income  number[0]
+	3:bonus   number[0]
+	4:total   number[0] computed_by {
+		return income + bonus
+	}
+}`
+
+func (s *Zuite) TestEdit_appliesAllEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+
+	err := NewEdit().
+		Set("name", NewText("Alice")).
+		Set("income", MustNewValue("100")).
+		Set("bonus", MustNewValue("20")).
+		Apply(member)
+	require.NoError(s.T(), err)
+
+	require.Equal(s.T(), `"Alice"`, member.MustGet("name").String())
+	require.Equal(s.T(), "120", member.MustGet("total").String())
+
+	err = member.Edit(func(e *Edit) error {
+		e.Unset("bonus")
+		e.Set("income", MustNewValue("200"))
+		return nil
+	})
+	require.NoError(s.T(), err)
+	require.False(s.T(), member.MustIsSet("bonus"))
+	require.Equal(s.T(), "undefined", member.MustGet("total").String())
+}
+
+func (s *Zuite) TestEdit_allOrNothing() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+	member.MustSet("bonus", MustNewValue("20"))
+
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", member)
+	require.Equal(s.T(), "100", household.MustGet("total_income").String())
+
+	cases := map[string]*Edit{
+		`"Mallory" not a valid value for constrained field name`: NewEdit().
+			Set("income", MustNewValue("500")).
+			Set("name", NewText("Mallory")),
+
+		"cannot assign value of type number[2] to number[0]": NewEdit().
+			Set("bonus", MustNewValue("30")).
+			Set("income", MustNewValue("1.25")),
+
+		"cannot assign to computed field total": NewEdit().
+			Set("income", MustNewValue("500")).
+			Set("total", MustNewValue("0")),
+
+		"unknown field not_a_field": NewEdit().
+			Unset("income").
+			Set("not_a_field", MustNewValue("0")),
+	}
+	for msg, edit := range cases {
+		err := edit.Apply(member)
+		require.EqualError(s.T(), err, msg)
+
+		require.False(s.T(), member.MustIsSet("name"), msg)
+		require.Equal(s.T(), "100", member.MustGet("income").String(), msg)
+		require.Equal(s.T(), "20", member.MustGet("bonus").String(), msg)
+		require.Equal(s.T(), "120", member.MustGet("total").String(), msg)
+		require.Equal(s.T(), "100", household.MustGet("total_income").String(), msg)
+	}
+}
+
+func (s *Zuite) TestEdit_allOrNothingOnSlicesAndParents() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	alice := defs.MustNewWorksheet("member")
+	alice.MustSet("income", MustNewValue("100"))
+	bob := defs.MustNewWorksheet("member")
+	bob.MustSet("income", MustNewValue("50"))
+
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", alice)
+
+	err := household.Edit(func(e *Edit) error {
+		e.Append("members", bob)
+		e.Del("members", 0)
+		e.Del("members", 5)
+		return nil
+	})
+	require.EqualError(s.T(), err, "index out of range")
+
+	require.Equal(s.T(), []Value{alice}, household.MustGetSlice("members"))
+	require.Equal(s.T(), "100", household.MustGet("total_income").String())
+	require.Len(s.T(), alice.parents["household"], 1)
+	require.Empty(s.T(), bob.parents)
+
+	// Edits to the child after the failed edit only reach the household
+	// through the restored parent pointers.
+	bob.MustSet("income", MustNewValue("70"))
+	alice.MustSet("income", MustNewValue("110"))
+	require.Equal(s.T(), "110", household.MustGet("total_income").String())
+}
+
+func (s *Zuite) TestEdit_abandoned() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+
+	err := member.Edit(func(e *Edit) error {
+		e.Set("income", MustNewValue("100"))
+		return fmt.Errorf("changed my mind")
+	})
+	require.EqualError(s.T(), err, "changed my mind")
+	require.False(s.T(), member.MustIsSet("income"))
+}
+
+func (s *Zuite) TestEdit_errorsMatchIndividualEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	household := defs.MustNewWorksheet("household")
+	member := defs.MustNewWorksheet("member")
+
+	cases := []struct {
+		edit     *Edit
+		ws       *Worksheet
+		expected error
+	}{
+		{NewEdit().Set("members", member), household, household.Set("members", member)},
+		{NewEdit().Unset("members"), household, household.Unset("members")},
+		{NewEdit().Append("name", alice), member, member.Append("name", alice)},
+		{NewEdit().Del("name", 0), member, member.Del("name", 0)},
+		{NewEdit().Del("not_a_field", 0), member, member.Del("not_a_field", 0)},
+		{NewEdit().Append("members", alice), household, household.Append("members", alice)},
+	}
+	for _, ex := range cases {
+		require.Error(s.T(), ex.expected)
+		require.EqualError(s.T(), ex.edit.Apply(ex.ws), ex.expected.Error())
+	}
+}
+
+type editorFunc func(current *Worksheet, proposed *Edit) (*Edit, error)
+
+func (fn editorFunc) OnEdit(current *Worksheet, proposed *Edit) (*Edit, error) {
+	return fn(current, proposed)
+}
+
+var defsForEditors = `
+type application worksheet {
+	1:name      text
+	2:ssn       text
+	3:income    number[0]
+	4:triggered number[0]
+	5:complete  bool computed_by {
+		return name != undefined && ssn != undefined && income != undefined
+	}
+}
+
+type cyclic_edits worksheet {
+	1:right bool
+	2:wrong bool computed_by {
+		return !right
+	}
+}`
+
+func (s *Zuite) TestEdit_editors() {
+	var calls int
+	defs := MustNewDefinitions(strings.NewReader(defsForEditors), Options{
+		Editors: map[string][]Editor{
+			"application": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					calls++
+					if current.MustGet("name").Equal(NewText("Joey")) {
+						return proposed.Set("name", NewText("Joey Pizzapie")), nil
+					}
+					return proposed, nil
+				}),
+				// Captures the first time the application is complete.
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					if proposed.IsSetting("triggered") || current.MustIsSet("triggered") {
+						return proposed, nil
+					}
+					if current.MustGet("complete").Equal(NewBool(true)) {
+						return proposed.Set("triggered", MustNewValue("1")), nil
+					}
+					return proposed, nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("application")
+
+	err := NewEdit().Set("name", NewText("Joey")).Set("ssn", NewText("123")).Apply(ws)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), `"Joey Pizzapie"`, ws.MustGet("name").String())
+	require.False(s.T(), ws.MustIsSet("triggered"))
+	require.Equal(s.T(), 2, calls)
+
+	err = NewEdit().Set("income", MustNewValue("100")).Apply(ws)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "1", ws.MustGet("triggered").String())
+
+	err = NewEdit().Unset("income").Apply(ws)
+	require.NoError(s.T(), err)
+	err = NewEdit().Set("income", MustNewValue("200")).Apply(ws)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "1", ws.MustGet("triggered").String())
+}
+
+func (s *Zuite) TestEdit_editorsRejectingEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEditors), Options{
+		Editors: map[string][]Editor{
+			"application": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					if proposed.IsEditing("ssn") && current.MustIsSet("income") {
+						return nil, fmt.Errorf("ssn is locked")
+					}
+					return proposed, nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("application")
+
+	err := NewEdit().Set("ssn", NewText("123")).Set("income", MustNewValue("5")).Apply(ws)
+	require.EqualError(s.T(), err, "ssn is locked")
+	require.False(s.T(), ws.MustIsSet("ssn"))
+	require.False(s.T(), ws.MustIsSet("income"))
+
+	err = NewEdit().Set("ssn", NewText("123")).Apply(ws)
+	require.NoError(s.T(), err)
+}
+
+func (s *Zuite) TestEdit_editorsCycling() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEditors), Options{
+		Editors: map[string][]Editor{
+			"cyclic_edits": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					wrong := current.MustGet("wrong").Equal(NewBool(true))
+					return proposed.Set("right", NewBool(wrong)), nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("cyclic_edits")
+
+	err := NewEdit().Set("right", NewBool(false)).Apply(ws)
+	require.EqualError(s.T(), err, "unstable edit: editors cycle after 2 iterations")
+	require.False(s.T(), ws.MustIsSet("right"))
+	require.False(s.T(), ws.MustIsSet("wrong"))
+}
+
+func (s *Zuite) TestEdit_editorsNotSettling() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEditors), Options{
+		Editors: map[string][]Editor{
+			"application": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					count := current.MustGet("income")
+					if count.Equal(vUndefined) {
+						return proposed.Set("income", MustNewValue("0")), nil
+					}
+					return proposed.Set("income", count.(*Number).Plus(MustNewValue("1").(*Number))), nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("application")
+
+	err := NewEdit().Set("name", NewText("Alice")).Apply(ws)
+	require.EqualError(s.T(), err, "unstable edit: editors did not settle after 100 iterations")
+	require.False(s.T(), ws.MustIsSet("name"))
+	require.False(s.T(), ws.MustIsSet("income"))
+}
+
+func (s *Zuite) TestEdit_editorsStableWhenReproposingSameValue() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEditors), Options{
+		Editors: map[string][]Editor{
+			"application": {
+				editorFunc(func(current *Worksheet, proposed *Edit) (*Edit, error) {
+					return proposed.Set("triggered", MustNewValue("7")), nil
+				}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("application")
+
+	err := NewEdit().Set("name", NewText("Alice")).Apply(ws)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "7", ws.MustGet("triggered").String())
+}
+
+func (s *Zuite) TestEdit_editorsUnknownWorksheet() {
+	_, err := NewDefinitions(strings.NewReader(defsForEditors), Options{
+		Editors: map[string][]Editor{
+			"not_here": nil,
+		},
+	})
+	require.EqualError(s.T(), err, "editors: unknown worksheet not_here")
+}
@@ -0,0 +1,188 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned when editing worksheets are of the types below, such that
+// callers can tell them apart with `errors.As`, and map them back to the
+// fields they concern, e.g.
+//
+//	var violation *worksheets.ConstraintViolation
+//	if errors.As(err, &violation) {
+//		// report violation.Field
+//	}
+
+// ConstraintViolation is the error returned when an edit is rejected because
+// it would violate a constraint: either the `constrained_by` constraint of a
+// field, in which case `Field`, and `Value` are set, or a constraint declared
+// in a `constraints` block of the worksheet, in which case `Constraint` is set.
+type ConstraintViolation struct {
+	// Worksheet is the worksheet whose constraint is violated.
+	Worksheet *Worksheet
+
+	// Field is the name of the constrained field, and Value the value it
+	// was set to.
+	Field string
+	Value Value
+
+	// Constraint is the name of the worksheet constraint violated.
+	Constraint string
+
+	// Message is the human-readable message declared along with the
+	// constraint, if any, and is then the message of the error.
+	Message string
+}
+
+func (e *ConstraintViolation) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("%s not a valid value for constrained field %s", e.Value, e.Field)
+	}
+	return fmt.Sprintf("%s violates constraint %s", e.Worksheet.def.name, e.Constraint)
+}
+
+// TypeMismatch is the error returned when assigning, appending, or inserting
+// a value which is not assignable to the type of the field, or of its
+// elements.
+type TypeMismatch struct {
+	// Worksheet, and Field, are the worksheet, and the name of the field
+	// edited, if known.
+	Worksheet *Worksheet
+	Field     string
+
+	// Value is the value which is not assignable to Type.
+	Value Value
+	Type  Type
+
+	op string
+}
+
+func (e *TypeMismatch) Error() string {
+	var (
+		valueStr                 string
+		valueAsText, valueIsText = e.Value.(*Text)
+		_, typIsEnum             = e.Type.(*EnumType)
+	)
+	if valueIsText && typIsEnum {
+		// We allow the value to leak into the error message in the special
+		// case of assigning a text to an enum.
+		valueStr = valueAsText.value
+	} else {
+		valueStr = fmt.Sprintf("value of type %s", e.Value.Type())
+	}
+	switch e.op {
+	case "append":
+		return fmt.Sprintf("cannot %s %s to []%s", e.op, valueStr, e.Type)
+	case "insert":
+		return fmt.Sprintf("cannot %s %s into []%s", e.op, valueStr, e.Type)
+	default:
+		return fmt.Sprintf("cannot %s %s to %s", e.op, valueStr, e.Type)
+	}
+}
+
+// withField records the field edited in errors about this field.
+func withField(err error, ws *Worksheet, field *Field) error {
+	if mismatch, ok := err.(*TypeMismatch); ok {
+		mismatch.Worksheet, mismatch.Field = ws, field.name
+	}
+	return err
+}
+
+// UnknownField is the error returned when referring to a field which the
+// definition of the worksheet does not have.
+type UnknownField struct {
+	Worksheet *Worksheet
+	Field     string
+}
+
+func (e *UnknownField) Error() string {
+	return fmt.Sprintf("unknown field %s", e.Field)
+}
+
+// ComputedFieldAssignment is the error returned when attempting to set a
+// computed field.
+type ComputedFieldAssignment struct {
+	Worksheet *Worksheet
+	Field     string
+}
+
+func (e *ComputedFieldAssignment) Error() string {
+	return fmt.Sprintf("cannot assign to computed field %s", e.Field)
+}
+
+// MissingRequiredField is the error reported by `Validate` for required fields
+// which are not set, or are empty slices.
+type MissingRequiredField struct {
+	Worksheet *Worksheet
+	Field     string
+}
+
+func (e *MissingRequiredField) Error() string {
+	return fmt.Sprintf("missing required field %s", e.Field)
+}
+
+// Violations is the error returned by `Validate`, listing all violations
+// found, in the order in which they were found.
+type Violations []error
+
+func (e Violations) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns all violations, for `errors.Is`, and `errors.As` to inspect
+// each of them, starting with Go 1.20.
+func (e Violations) Unwrap() []error {
+	return e
+}
+
+// As finds the first violation matching target, for `errors.As` to find
+// violations with earlier versions of Go, which only unwrap single errors.
+func (e Violations) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConcurrentModification is the error returned when storing a worksheet
+// which was concurrently updated in the store, since it was loaded.
+type ConcurrentModification struct {
+	Worksheet *Worksheet
+
+	// Err is the underlying error reported by the store, if any.
+	Err error
+}
+
+func (e *ConcurrentModification) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("concurrent update detected (%s)", e.Err)
+	}
+	return "concurrent update detected"
+}
+
+func (e *ConcurrentModification) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,145 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defsForErrors = `
+type applicant worksheet {
+	1:ssn       text constrained_by {
+		return len(ssn) == 9
+	}
+	2:age       number[0]
+	3:kind      kind
+	4:incomes   []number[2]
+	5:is_adult  bool computed_by {
+		return age >= 18
+	}
+}
+
+type kind enum {
+	"primary",
+	"co",
+}`
+
+func (s *Zuite) TestErrors_constraintViolation() {
+	defs := MustNewDefinitions(strings.NewReader(defsForErrors))
+	ws := defs.MustNewWorksheet("applicant")
+
+	err := ws.Set("ssn", NewText("123"))
+	require.EqualError(s.T(), err, `"123" not a valid value for constrained field ssn`)
+
+	var violation *ConstraintViolation
+	require.True(s.T(), errors.As(fmt.Errorf("wrapped: %w", err), &violation))
+	require.Equal(s.T(), &ConstraintViolation{
+		Worksheet: ws,
+		Field:     "ssn",
+		Value:     NewText("123"),
+	}, violation)
+}
+
+func (s *Zuite) TestErrors_violationsAs() {
+	defs := MustNewDefinitions(strings.NewReader(defsForErrors))
+	ws := defs.MustNewWorksheet("applicant")
+	violation := &ConstraintViolation{Worksheet: ws, Field: "ssn", Value: NewText("123")}
+	mismatch := &TypeMismatch{Worksheet: ws, Field: "kind", Value: NewText("third")}
+	violations := Violations{fmt.Errorf("wrapped: %w", mismatch), violation}
+
+	// As is used by errors.As prior to Go 1.20, which ignores Unwrap() []error.
+	var asViolation *ConstraintViolation
+	require.True(s.T(), violations.As(&asViolation))
+	require.Equal(s.T(), violation, asViolation)
+
+	var asMismatch *TypeMismatch
+	require.True(s.T(), violations.As(&asMismatch))
+	require.Equal(s.T(), mismatch, asMismatch)
+
+	var missing *MissingRequiredField
+	require.False(s.T(), violations.As(&missing))
+	require.True(s.T(), errors.As(violations, &asViolation))
+}
+
+func (s *Zuite) TestErrors_typeMismatch() {
+	defs := MustNewDefinitions(strings.NewReader(defsForErrors))
+	ws := defs.MustNewWorksheet("applicant")
+
+	cases := []struct {
+		err      error
+		field    string
+		value    Value
+		expected string
+	}{
+		{
+			ws.Set("age", NewText("old")),
+			"age", NewText("old"),
+			"cannot assign value of type text to number[0]",
+		},
+		{
+			ws.Set("kind", NewText("third")),
+			"kind", NewText("third"),
+			"cannot assign third to kind",
+		},
+		{
+			ws.Append("incomes", NewBool(true)),
+			"incomes", NewBool(true),
+			"cannot append value of type bool to []number[2]",
+		},
+		{
+			ws.Insert("incomes", 0, MustNewValue("1.234")),
+			"incomes", MustNewValue("1.234"),
+			"cannot insert value of type number[3] into []number[2]",
+		},
+	}
+	for _, ex := range cases {
+		require.EqualError(s.T(), ex.err, ex.expected)
+
+		var mismatch *TypeMismatch
+		require.True(s.T(), errors.As(ex.err, &mismatch), ex.expected)
+		require.Equal(s.T(), ws, mismatch.Worksheet)
+		require.Equal(s.T(), ex.field, mismatch.Field)
+		require.Equal(s.T(), ex.value, mismatch.Value)
+	}
+}
+
+func (s *Zuite) TestErrors_unknownFieldAndComputedFieldAssignment() {
+	defs := MustNewDefinitions(strings.NewReader(defsForErrors))
+	ws := defs.MustNewWorksheet("applicant")
+
+	for _, err := range []error{
+		ws.Set("nope", NewText("Alice")),
+		ws.Append("nope", NewText("Alice")),
+		ws.Clear("nope"),
+		func() error {
+			_, err := ws.Get("nope")
+			return err
+		}(),
+	} {
+		var unknown *UnknownField
+		require.True(s.T(), errors.As(err, &unknown), err.Error())
+		require.Equal(s.T(), &UnknownField{ws, "nope"}, unknown)
+		require.EqualError(s.T(), err, "unknown field nope")
+	}
+
+	err := ws.Set("is_adult", NewBool(true))
+	require.EqualError(s.T(), err, "cannot assign to computed field is_adult")
+
+	var assignment *ComputedFieldAssignment
+	require.True(s.T(), errors.As(err, &assignment))
+	require.Equal(s.T(), &ComputedFieldAssignment{ws, "is_adult"}, assignment)
+}
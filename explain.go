@@ -0,0 +1,361 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ExplanationKind describes what a node of an explanation stands for.
+type ExplanationKind string
+
+const (
+	// ExplainField is the read of a field. When the field is computed, the
+	// explanation of its value is the only child of the node.
+	ExplainField ExplanationKind = "field"
+
+	// ExplainSelector is a selector going through other worksheets, such as
+	// `borrower.income`. Its children are the fields read along the way.
+	ExplainSelector ExplanationKind = "selector"
+
+	// ExplainLiteral is a literal value such as `42` or `"Alice"`.
+	ExplainLiteral ExplanationKind = "literal"
+
+	// ExplainOperator is a unary or binary operation. Its children are the
+	// operands which were evaluated.
+	ExplainOperator ExplanationKind = "operator"
+
+	// ExplainCall is a function call. Its children are the arguments which
+	// were evaluated.
+	ExplainCall ExplanationKind = "call"
+
+	// ExplainPlugin is a value computed by a plugin. Its children are the
+	// arguments passed to the plugin.
+	ExplainPlugin ExplanationKind = "plugin"
+)
+
+// Explanation is the provenance trace of a value, i.e. the tree of all
+// sub-expressions evaluated to compute it, along with their concrete value.
+//
+// Since functions and boolean operators evaluate their arguments lazily, only
+// sub-expressions which were evaluated are part of the explanation. For
+// instance, explaining `if(cond, x, y)` when `cond` is true yields `cond` and
+// `x`, but not `y`.
+type Explanation struct {
+	Kind ExplanationKind
+
+	// Label is the path of the field read for fields, the selector for
+	// selectors, the operator for operators, the name of the function for
+	// calls, and `external` for plugins.
+	Label string
+
+	// Value is the concrete value of the sub-expression.
+	Value Value
+
+	// Worksheet and Field are the worksheet and field read, and are only set
+	// for fields.
+	Worksheet *Worksheet
+	Field     *Field
+
+	Children []*Explanation
+}
+
+// Explain returns the provenance trace of the field `name`.
+func (ws *Worksheet) Explain(name string) (*Explanation, error) {
+	defer ws.rlock()()
+
+	field, value, err := ws.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return explainField(ws, field, value, name)
+}
+
+// Inputs returns all fields which are read, and aren't computed, i.e. all
+// raw values the explained value ultimately derives from. Inputs are listed
+// in the order in which they were read, and may repeat.
+func (ex *Explanation) Inputs() []*Explanation {
+	var inputs []*Explanation
+	ex.walk(func(node *Explanation) {
+		if node.Kind == ExplainField && !node.Field.IsComputedBy() {
+			inputs = append(inputs, node)
+		}
+	})
+	return inputs
+}
+
+// Calls returns all function calls made, in the order in which they were
+// made.
+func (ex *Explanation) Calls() []*Explanation {
+	var calls []*Explanation
+	ex.walk(func(node *Explanation) {
+		if node.Kind == ExplainCall {
+			calls = append(calls, node)
+		}
+	})
+	return calls
+}
+
+func (ex *Explanation) walk(fn func(node *Explanation)) {
+	fn(ex)
+	for _, child := range ex.Children {
+		child.walk(fn)
+	}
+}
+
+// String renders the explanation as an indented tree, one sub-expression per
+// line, e.g.
+//
+//	can_take_mortgage = false
+//	  && = false
+//	    > = false
+//	      income = 40000
+//	      50000
+func (ex *Explanation) String() string {
+	var b bytes.Buffer
+	ex.print(&b, 0)
+	return b.String()
+}
+
+func (ex *Explanation) print(b *bytes.Buffer, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	switch ex.Kind {
+	case ExplainLiteral:
+		b.WriteString(explainValue(ex.Value))
+	case ExplainCall:
+		fmt.Fprintf(b, "%s() = %s", ex.Label, explainValue(ex.Value))
+	default:
+		fmt.Fprintf(b, "%s = %s", ex.Label, explainValue(ex.Value))
+	}
+	b.WriteString("\n")
+	for _, child := range ex.Children {
+		child.print(b, depth+1)
+	}
+}
+
+// explainValue renders values compactly, with worksheets rendered as their
+// name and id rather than all their fields.
+func explainValue(value Value) string {
+	switch v := value.(type) {
+	case *Worksheet:
+		return fmt.Sprintf("%s(%s)", v.def.name, v.Id())
+	case *Slice:
+		elements := make([]string, len(v.elements))
+		for i, element := range v.elements {
+			elements[i] = explainValue(element.value)
+		}
+		return "[" + strings.Join(elements, " ") + "]"
+	default:
+		return value.String()
+	}
+}
+
+func explainField(ws *Worksheet, field *Field, value Value, path string) (*Explanation, error) {
+	ex := &Explanation{
+		Kind:      ExplainField,
+		Label:     path,
+		Value:     value,
+		Worksheet: ws,
+		Field:     field,
+	}
+	if field.computedBy != nil {
+		child, err := explain(ws, field.computedBy)
+		if err != nil {
+			return nil, err
+		}
+		ex.Children = []*Explanation{child}
+	}
+	return ex, nil
+}
+
+func explain(ws *Worksheet, expr expression) (*Explanation, error) {
+	switch e := expr.(type) {
+	case *Undefined, *Number, *Text, *Bool:
+		return &Explanation{
+			Kind:  ExplainLiteral,
+			Value: e.(Value),
+		}, nil
+
+	case tSelector:
+		return explainSelector(ws, e)
+
+	case *tReturn:
+		return explain(ws, e.expr)
+
+	case *tUnop:
+		operand := &tracedExpr{expr: e.expr}
+		return explainTraced(ws, ExplainOperator, opSymbols[e.op], &tUnop{e.op, operand}, operand)
+
+	case *tBinop:
+		label := opSymbols[e.op]
+		if e.round != nil {
+			label = fmt.Sprintf("%s round %s", label, e.round)
+		}
+		left, right := &tracedExpr{expr: e.left}, &tracedExpr{expr: e.right}
+		return explainTraced(ws, ExplainOperator, label, &tBinop{e.op, left, right, e.round}, left, right)
+
+	case *tCall:
+		args := make([]expression, len(e.args))
+		traced := make([]*tracedExpr, len(e.args))
+		for i, arg := range e.args {
+			traced[i] = &tracedExpr{expr: arg}
+			args[i] = traced[i]
+		}
+		return explainTraced(ws, ExplainCall, e.name.String(), &tCall{e.name, args, e.round}, traced...)
+
+	case *tIndex:
+		operand, index := &tracedExpr{expr: e.expr}, &tracedExpr{expr: e.index}
+		return explainTraced(ws, ExplainOperator, "[]", &tIndex{operand, index}, operand, index)
+
+	case *tRange:
+		operand := &tracedExpr{expr: e.expr}
+		traced := []*tracedExpr{operand}
+		ranged := &tRange{expr: operand}
+		if e.from != nil {
+			from := &tracedExpr{expr: e.from}
+			traced = append(traced, from)
+			ranged.from = from
+		}
+		if e.to != nil {
+			to := &tracedExpr{expr: e.to}
+			traced = append(traced, to)
+			ranged.to = to
+		}
+		return explainTraced(ws, ExplainOperator, "[:]", ranged, traced...)
+
+	case *tMember:
+		operand := &tracedExpr{expr: e.expr}
+		return explainTraced(ws, ExplainSelector, "."+e.path.String(), &tMember{operand, e.path}, operand)
+
+	case *ePlugin:
+		ex := &Explanation{
+			Kind:  ExplainPlugin,
+			Label: "external",
+		}
+		for _, arg := range e.selectors() {
+			child, err := explainSelector(ws, arg)
+			if err != nil {
+				return nil, err
+			}
+			ex.Children = append(ex.Children, child)
+		}
+		value, err := e.compute(ws)
+		if err != nil {
+			return nil, err
+		}
+		ex.Value = value
+		return ex, nil
+
+	default:
+		panic(fmt.Sprintf("unexpected expression %T", expr))
+	}
+}
+
+// tracedExpr wraps an expression to capture its explanation, if and when it
+// is computed. This lets us rely on the regular evaluation of operators and
+// functions, with their laziness, to explain them.
+type tracedExpr struct {
+	expr expression
+	ex   *Explanation
+}
+
+func (t *tracedExpr) selectors() []tSelector {
+	return t.expr.selectors()
+}
+
+func (t *tracedExpr) compute(ws *Worksheet) (Value, error) {
+	ex, err := explain(ws, t.expr)
+	if err != nil {
+		return nil, err
+	}
+	t.ex = ex
+	return ex.Value, nil
+}
+
+func explainTraced(ws *Worksheet, kind ExplanationKind, label string, expr expression, operands ...*tracedExpr) (*Explanation, error) {
+	value, err := expr.compute(ws)
+	if err != nil {
+		return nil, err
+	}
+	ex := &Explanation{
+		Kind:  kind,
+		Label: label,
+		Value: value,
+	}
+	for _, operand := range operands {
+		if operand.ex != nil {
+			ex.Children = append(ex.Children, operand.ex)
+		}
+	}
+	return ex, nil
+}
+
+func explainSelector(ws *Worksheet, selector tSelector) (*Explanation, error) {
+	var reads []*Explanation
+	if err := explainReads(ws, selector, "", &reads); err != nil {
+		return nil, err
+	}
+	if len(selector) == 1 {
+		return reads[0], nil
+	}
+
+	value, err := selector.compute(ws)
+	if err != nil {
+		return nil, err
+	}
+	return &Explanation{
+		Kind:     ExplainSelector,
+		Label:    selector.String(),
+		Value:    value,
+		Children: reads,
+	}, nil
+}
+
+// explainReads collects the explanation of all fields read when evaluating
+// the selector, prefixing their path with `prefix`.
+func explainReads(ws *Worksheet, selector tSelector, prefix string, reads *[]*Explanation) error {
+	field, value, err := ws.get(selector[0])
+	if err != nil {
+		return err
+	}
+	path := prefix + selector[0]
+	read, err := explainField(ws, field, value, path)
+	if err != nil {
+		return err
+	}
+	*reads = append(*reads, read)
+
+	if len(selector) == 1 {
+		return nil
+	}
+	return explainReadsFrom(value, selector[1:], path, reads)
+}
+
+// explainReadsFrom collects the explanation of all fields read when evaluating
+// the selector on `value`, be it a worksheet, or a possibly nested slice of
+// worksheets.
+func explainReadsFrom(value Value, selector tSelector, path string, reads *[]*Explanation) error {
+	switch v := value.(type) {
+	case *Worksheet:
+		return explainReads(v, selector, path+".", reads)
+	case *Slice:
+		for i, elem := range v.elements {
+			if err := explainReadsFrom(elem.value, selector, fmt.Sprintf("%s[%d]", path, i), reads); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+var defsForExplain = `
+type mortgage worksheet {
+	1:borrowers         []borrower
+	2:property          property
+	3:total_income      number[0] computed_by {
+		return sum(borrowers.income)
+	}
+	4:can_take_mortgage bool computed_by {
+		return total_income > property.price / 4 round down 0 && !property.condemned
+	}
+	5:label             text computed_by {
+		return if(can_take_mortgage, "approved", first_of(property.name, "declined"))
+	}
+}
+
+type borrower worksheet {
+	1:income number[0]
+}
+
+type property worksheet {
+	1:price     number[0]
+	2:condemned bool
+	3:name      text
+}`
+
+func (s *Zuite) explainFixture() *Worksheet {
+	defs := MustNewDefinitions(strings.NewReader(defsForExplain))
+
+	property := defs.MustNewWorksheet("property")
+	property.MustSet("price", MustNewValue("400000"))
+	property.MustSet("condemned", MustNewValue("false"))
+
+	ws := defs.MustNewWorksheet("mortgage")
+	ws.MustSet("property", property)
+	for _, income := range []string{"40000", "50000"} {
+		borrower := defs.MustNewWorksheet("borrower")
+		borrower.MustSet("income", MustNewValue(income))
+		ws.MustAppend("borrowers", borrower)
+	}
+	return ws
+}
+
+func (s *Zuite) TestExplain() {
+	ws := s.explainFixture()
+
+	ex, err := ws.Explain("can_take_mortgage")
+	require.NoError(s.T(), err)
+
+	expected := `can_take_mortgage = false
+  && = false
+    > = false
+      total_income = 90000
+        sum() = 90000
+          borrowers.income = [40000 50000]
+            borrowers = [borrower(id) borrower(id)]
+            borrowers[0].income = 40000
+            borrowers[1].income = 50000
+      / round down 0 = 100000
+        property.price = 400000
+          property = property(id)
+          property.price = 400000
+        4
+`
+	require.Equal(s.T(), expected, s.withoutIds(ex.String()))
+
+	require.Equal(s.T(), ExplainField, ex.Kind)
+	require.Equal(s.T(), ws, ex.Worksheet)
+	require.Equal(s.T(), MustNewValue("false"), ex.Value)
+
+	var inputs []string
+	for _, input := range ex.Inputs() {
+		inputs = append(inputs, input.Label)
+	}
+	require.Equal(s.T(), []string{
+		"borrowers",
+		"borrowers[0].income",
+		"borrowers[1].income",
+		"property",
+		"property.price",
+	}, inputs)
+
+	calls := ex.Calls()
+	require.Len(s.T(), calls, 1)
+	require.Equal(s.T(), "sum", calls[0].Label)
+}
+
+func (s *Zuite) TestExplain_onlyEvaluatedBranches() {
+	ws := s.explainFixture()
+	ws.MustGet("property").(*Worksheet).MustSet("price", MustNewValue("100000"))
+
+	ex, err := ws.Explain("label")
+	require.NoError(s.T(), err)
+
+	// The else-branch of the `if` is not evaluated, and `first_of` does not
+	// show up.
+	require.Equal(s.T(), MustNewValue(`"approved"`), ex.Value)
+	require.Len(s.T(), ex.Children[0].Children, 2)
+	require.Equal(s.T(), "can_take_mortgage", ex.Children[0].Children[0].Label)
+	require.Equal(s.T(), ExplainLiteral, ex.Children[0].Children[1].Kind)
+
+	var calls []string
+	for _, call := range ex.Calls() {
+		calls = append(calls, call.Label)
+	}
+	require.Equal(s.T(), []string{"if", "sum"}, calls)
+
+	// The `!property.condemned` operand is now evaluated.
+	and := ex.Children[0].Children[0].Children[0]
+	require.Equal(s.T(), "&&", and.Label)
+	require.Len(s.T(), and.Children, 2)
+	require.Equal(s.T(), "!", and.Children[1].Label)
+	require.Equal(s.T(), MustNewValue("true"), and.Children[1].Value)
+}
+
+func (s *Zuite) TestExplain_inputField() {
+	ws := s.explainFixture()
+
+	ex, err := ws.Explain("property")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), ExplainField, ex.Kind)
+	require.Empty(s.T(), ex.Children)
+	require.Len(s.T(), ex.Inputs(), 1)
+
+	_, err = ws.Explain("not_a_field")
+	require.EqualError(s.T(), err, "unknown field not_a_field")
+}
+
+func (s *Zuite) TestExplain_plugin() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type simple worksheet {
+		1:age  number[0]
+		2:name text computed_by { external }
+	}`), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"simple": {
+				"name": sayAlice([]string{"age"}),
+			},
+		},
+	})
+	ws := defs.MustNewWorksheet("simple")
+	ws.MustSet("age", MustNewValue("73"))
+
+	ex, err := ws.Explain("name")
+	require.NoError(s.T(), err)
+
+	expected := `name = "Alice"
+  external = "Alice"
+    age = 73
+`
+	require.Equal(s.T(), expected, ex.String())
+	require.Equal(s.T(), ExplainPlugin, ex.Children[0].Kind)
+}
+
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// withoutIds strips worksheet ids from explanations, which otherwise vary
+// from run to run.
+func (s *Zuite) withoutIds(text string) string {
+	return uuidPattern.ReplaceAllString(text, "id")
+}
+
+func (s *Zuite) TestExplain_indexing() {
+	defs := MustNewDefinitions(strings.NewReader(defsForIndexing))
+	loan := defs.MustNewWorksheet("loan")
+	for _, name := range []string{"Alice", "Bob"} {
+		borrower := defs.MustNewWorksheet("borrower")
+		borrower.MustSet("first_name", NewText(name))
+		loan.MustAppend("borrowers", borrower)
+	}
+
+	ex, err := loan.Explain("primary_name")
+	require.NoError(s.T(), err)
+	expected := `primary_name = "Alice"
+  .first_name = "Alice"
+    [] = borrower(id)
+      borrowers = [borrower(id) borrower(id)]
+      0
+`
+	require.Equal(s.T(), expected, s.withoutIds(ex.String()))
+}
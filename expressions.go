@@ -14,6 +14,8 @@ package worksheets
 
 import (
 	"fmt"
+	"math/big"
+	"sort"
 	"strings"
 )
 
@@ -36,6 +38,9 @@ var _ = []expression{
 	&tBinop{},
 	&tReturn{},
 	&tCall{},
+	&tIndex{},
+	&tRange{},
+	&tMember{},
 }
 
 func (e *tExternal) selectors() []tSelector {
@@ -99,7 +104,7 @@ func (e tSelector) selectors() []tSelector {
 }
 
 func (e tSelector) compute(ws *Worksheet) (Value, error) {
-	_, value, err := ws.get(e[0])
+	field, value, err := ws.get(e[0])
 	if err != nil {
 		return nil, err
 	}
@@ -110,23 +115,28 @@ func (e tSelector) compute(ws *Worksheet) (Value, error) {
 	}
 
 	// recursive case
-	if _, ok := value.(*Undefined); ok {
-		return value, nil
-	} else if selectedWs, ok := value.(*Worksheet); ok {
-		return tSelector(e[1:]).compute(selectedWs)
-	} else if selectedSlice, ok := value.(*Slice); ok {
-		subWsDef, ok := ws.def.fieldsByName[e[0]].Type().(*SliceType).ElementType().(*Definition)
+	return tSelector(e[1:]).selectFrom(field.typ, value)
+}
+
+// selectFrom selects from `value` of type `typ`. Selecting from a slice yields
+// the slice of what is selected from each of its elements, at any depth, such
+// that with `borrowers []borrower`, and `incomes []income`, the selector
+// `borrowers.incomes.amount` yields a slice of slices of amounts.
+func (e tSelector) selectFrom(typ Type, value Value) (Value, error) {
+	switch v := value.(type) {
+	case *Undefined:
+		return v, nil
+	case *Worksheet:
+		return e.compute(v)
+	case *Slice:
+		elementType := typ.(*SliceType).elementType
+		selectedType, ok := e.selectType(elementType)
 		if !ok {
-			return nil, fmt.Errorf("sorry! more complex selectors are not supported yet!")
+			return nil, fmt.Errorf("unknown field %s", e)
 		}
-		var elementType = subWsDef.fieldsByName[e[1]].Type()
 		var elements []sliceElement
-		for _, elem := range selectedSlice.elements {
-			subWs, ok := elem.value.(*Worksheet)
-			if !ok {
-				return nil, fmt.Errorf("sorry! more complex selectors are not supported yet!")
-			}
-			subValue, err := tSelector(e[1:]).compute(subWs)
+		for _, elem := range v.elements {
+			subValue, err := e.selectFrom(elementType, elem.value)
 			if err != nil {
 				return nil, err
 			}
@@ -136,11 +146,190 @@ func (e tSelector) compute(ws *Worksheet) (Value, error) {
 		}
 		return &Slice{
 			elements: elements,
-			typ:      &SliceType{elementType},
+			typ:      &SliceType{selectedType},
 		}, nil
 	}
 
-	return nil, fmt.Errorf("sorry! more complex selectors are not supported yet!")
+	return nil, fmt.Errorf("cannot select %s from %s", e, value.Type())
+}
+
+// selectType returns the type of values selected from values of type `typ`.
+func (e tSelector) selectType(typ Type) (Type, bool) {
+	switch t := typ.(type) {
+	case *Definition:
+		field, ok := t.fieldsByName[e[0]]
+		if !ok {
+			return nil, false
+		}
+		if len(e) == 1 {
+			return field.typ, true
+		}
+		return tSelector(e[1:]).selectType(field.typ)
+	case *SliceType:
+		elementType, ok := e.selectType(t.elementType)
+		if !ok {
+			return nil, false
+		}
+		return &SliceType{elementType}, true
+	}
+	return nil, false
+}
+
+func (e *tIndex) selectors() []tSelector {
+	return append(e.expr.selectors(), e.index.selectors()...)
+}
+
+func (e *tIndex) compute(ws *Worksheet) (Value, error) {
+	slice, ok, err := computeSlice(ws, e.expr, "index")
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	index, ok, err := computeIndex(ws, e.index)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	if index < 0 || len(slice.elements) <= index {
+		return vUndefined, nil
+	}
+	return slice.elements[index].value, nil
+}
+
+func (e *tRange) selectors() []tSelector {
+	selectors := e.expr.selectors()
+	for _, bound := range []expression{e.from, e.to} {
+		if bound != nil {
+			selectors = append(selectors, bound.selectors()...)
+		}
+	}
+	return selectors
+}
+
+func (e *tRange) compute(ws *Worksheet) (Value, error) {
+	slice, ok, err := computeSlice(ws, e.expr, "slice")
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	from, to := 0, len(slice.elements)
+	if e.from != nil {
+		from, ok, err = computeIndex(ws, e.from)
+		if err != nil || !ok {
+			return vUndefined, err
+		}
+	}
+	if e.to != nil {
+		to, ok, err = computeIndex(ws, e.to)
+		if err != nil || !ok {
+			return vUndefined, err
+		}
+	}
+	if from < 0 || to < from || len(slice.elements) < to {
+		return vUndefined, nil
+	}
+	return &Slice{
+		typ:      slice.typ,
+		elements: slice.elements[from:to:to],
+	}, nil
+}
+
+// computeSlice computes the slice to index, or re-slice, which is undefined if
+// the expression is.
+func computeSlice(ws *Worksheet, expr expression, op string) (*Slice, bool, error) {
+	value, err := expr.compute(ws)
+	if err != nil {
+		return nil, false, err
+	}
+	switch v := value.(type) {
+	case *Undefined:
+		return nil, false, nil
+	case *Slice:
+		return v, true, nil
+	default:
+		return nil, false, fmt.Errorf("cannot %s %s", op, value.Type())
+	}
+}
+
+// computeIndex computes an index, which is undefined if the expression is.
+func computeIndex(ws *Worksheet, expr expression) (int, bool, error) {
+	value, err := expr.compute(ws)
+	if err != nil {
+		return 0, false, err
+	}
+	switch v := value.(type) {
+	case *Undefined:
+		return 0, false, nil
+	case *Number:
+		if v.typ.scale == 0 {
+			return int(v.value), true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("index must be number[0], was %s", value.Type())
+}
+
+func (e *tMember) selectors() []tSelector {
+	selectors := e.expr.selectors()
+	roots, _ := rootSelectors(e.expr)
+	for _, root := range roots {
+		path := append(append(tSelector(nil), root...), e.path...)
+		selectors = append(selectors, path)
+	}
+	return selectors
+}
+
+// rootSelectors returns the selectors whose values an expression selects
+// from, e.g. `borrowers` for `borrowers[0]`, such that selecting `first_name`
+// from it depends on `borrowers.first_name`. Expressions which may yield
+// values selected otherwise, e.g. `len(borrowers)`, are reported as not
+// tracked, and selecting from them is rejected when parsing.
+func rootSelectors(expr expression) ([]tSelector, bool) {
+	switch e := expr.(type) {
+	case Value:
+		return nil, true
+	case tSelector:
+		return []tSelector{e}, true
+	case *tIndex:
+		return rootSelectors(e.expr)
+	case *tRange:
+		return rootSelectors(e.expr)
+	case *tMember:
+		roots, ok := rootSelectors(e.expr)
+		if !ok {
+			return nil, false
+		}
+		var paths []tSelector
+		for _, root := range roots {
+			paths = append(paths, append(append(tSelector(nil), root...), e.path...))
+		}
+		return paths, true
+	case *tCall:
+		if len(e.name) != 1 {
+			return nil, false
+		}
+		indices, ok := functionsSelectingFromArgs[e.name[0]]
+		if !ok {
+			return nil, false
+		}
+		var roots []tSelector
+		for i, arg := range e.args {
+			if indices != nil && !indices[i] {
+				continue
+			}
+			argRoots, ok := rootSelectors(arg)
+			if !ok {
+				return nil, false
+			}
+			roots = append(roots, argRoots...)
+		}
+		return roots, true
+	}
+	return nil, false
+}
+
+func (e *tMember) compute(ws *Worksheet) (Value, error) {
+	value, err := e.expr.compute(ws)
+	if err != nil {
+		return nil, err
+	}
+	return e.path.selectFrom(value.Type(), value)
 }
 
 func (e *tUnop) selectors() []tSelector {
@@ -293,6 +482,17 @@ func (e *tCall) selectors() []tSelector {
 	for _, expr := range e.args {
 		args = append(args, expr.selectors()...)
 	}
+
+	// `sort_by(slice, "field")` also depends on the field of each element.
+	if len(e.name) == 1 && e.name[0] == "sort_by" && len(e.args) == 2 {
+		roots, _ := rootSelectors(e.args[0])
+		if name, ok := e.args[1].(*Text); ok {
+			path := strings.Split(name.value, ".")
+			for _, root := range roots {
+				args = append(args, append(append(tSelector(nil), root...), path...))
+			}
+		}
+	}
 	return args
 }
 
@@ -374,6 +574,22 @@ func (args *fnArgs) has(index int) bool {
 	return index < args.num()
 }
 
+// getSlice gets the argument at `index`, which must be a slice, or undefined.
+func (args *fnArgs) getSlice(index int) (*Slice, bool, error) {
+	arg, err := args.get(index)
+	if err != nil {
+		return nil, false, err
+	}
+	switch v := arg.(type) {
+	case *Undefined:
+		return nil, false, nil
+	case *Slice:
+		return v, true, nil
+	default:
+		return nil, false, fmt.Errorf("argument #%d expected to be slice", index+1)
+	}
+}
+
 func (args *fnArgs) get(index int) (Value, error) {
 	// compute?
 	if expr := args.exprs[index]; expr != nil {
@@ -390,7 +606,25 @@ func (args *fnArgs) get(index int) (Value, error) {
 // (`avg`) needs a rounding mode to know the precision needed for the average
 // to calculate.
 var functionsRequiringRound = map[string]bool{
-	"avg": true,
+	"avg":        true,
+	"median":     true,
+	"percentile": true,
+	"stddev":     true,
+}
+
+// functionsSelectingFromArgs lists functions returning elements of their
+// arguments, e.g. `last` or `first_of`, along with the indices of these
+// arguments, or nil for all arguments. Selecting from their result depends on
+// the same fields as selecting from these arguments.
+var functionsSelectingFromArgs = map[string]map[int]bool{
+	"last":     {0: true},
+	"sort":     {0: true},
+	"sort_by":  {0: true},
+	"distinct": {0: true},
+	"reverse":  {0: true},
+	"if":       {1: true, 2: true},
+	"first_of": nil,
+	"slice":    nil,
 }
 
 var functions = map[string]func(args *fnArgs) (Value, error){
@@ -497,11 +731,21 @@ var functions = map[string]func(args *fnArgs) (Value, error){
 			}
 		}
 	},
-	"first_of": rFirstOf,
-	"min":      rMin,
-	"max":      rMax,
-	"slice":    rSlice,
-	"avg":      rAvg,
+	"first_of":   rFirstOf,
+	"min":        rMin,
+	"max":        rMax,
+	"slice":      rSlice,
+	"avg":        rAvg,
+	"last":       rLast,
+	"index_of":   rIndexOf,
+	"contains":   rContains,
+	"sort":       rSort,
+	"sort_by":    rSortBy,
+	"distinct":   rDistinct,
+	"reverse":    rReverse,
+	"median":     rMedian,
+	"percentile": rPercentile,
+	"stddev":     rStddev,
 }
 
 func rFirstOf(args *fnArgs) (Value, error) {
@@ -525,6 +769,51 @@ func rFirstOf(args *fnArgs) (Value, error) {
 	return vUndefined, nil
 }
 
+func rLast(args *fnArgs) (Value, error) {
+	if err := args.checkArgsNum(1); err != nil {
+		return nil, err
+	}
+	slice, ok, err := args.getSlice(0)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	if len(slice.elements) == 0 {
+		return vUndefined, nil
+	}
+	return slice.elements[len(slice.elements)-1].value, nil
+}
+
+func rIndexOf(args *fnArgs) (Value, error) {
+	if err := args.checkArgsNum(2); err != nil {
+		return nil, err
+	}
+	slice, ok, err := args.getSlice(0)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	value, err := args.get(1)
+	if err != nil {
+		return nil, err
+	}
+	for i, element := range slice.elements {
+		if valuesEqual(element.value, value) {
+			return NewNumberFromInt(i), nil
+		}
+	}
+	return NewNumberFromInt(-1), nil
+}
+
+func rContains(args *fnArgs) (Value, error) {
+	index, err := rIndexOf(args)
+	if err != nil {
+		return nil, err
+	}
+	if num, ok := index.(*Number); ok {
+		return NewBool(num.value != -1), nil
+	}
+	return index, nil
+}
+
 type foldNumbers interface {
 	update(value *Number)
 	result() Value
@@ -534,31 +823,40 @@ func rFoldNumbers(f foldNumbers, args *fnArgs, minArgs int) (Value, error) {
 	if err := args.checkMinArgsNum(minArgs); err != nil {
 		return nil, err
 	}
+	undefined, err := foldArgs(f, args)
+	if err != nil {
+		return nil, err
+	}
+	if undefined {
+		return vUndefined, nil
+	}
+	return f.result(), nil
+}
+
+// foldArgs folds all numbers of the arguments, flattening slices at any depth,
+// and reports whether an undefined element was encountered, in which case the
+// fold is undefined. Empty slices, at any depth, contribute no numbers.
+func foldArgs(f foldNumbers, args *fnArgs) (bool, error) {
 	for i := 0; i < args.num(); i++ {
 		arg, err := args.get(i)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 		switch value := arg.(type) {
 		case *Undefined:
-			return vUndefined, nil
+			return true, nil
 		case *Number:
 			f.update(value)
 		case *Slice:
-			if value.Len() != 0 {
-				result, err := rFoldNumbers(f, newFnArgs(args.ws, args.round, value.Elements()), 0)
-				if err != nil {
-					return nil, err
-				}
-				if result == vUndefined {
-					return vUndefined, nil
-				}
+			undefined, err := foldArgs(f, newFnArgs(args.ws, args.round, value.Elements()))
+			if err != nil || undefined {
+				return undefined, err
 			}
 		default:
-			return nil, fmt.Errorf("encountered non-numerical argument")
+			return false, fmt.Errorf("encountered non-numerical argument")
 		}
 	}
-	return f.result(), nil
+	return false, nil
 }
 
 type sumFolder struct {
@@ -588,6 +886,9 @@ func (f *minFolder) update(value *Number) {
 }
 
 func (f *minFolder) result() Value {
+	if f.min == nil {
+		return vUndefined
+	}
 	return f.min
 }
 
@@ -606,6 +907,9 @@ func (f *maxFolder) update(value *Number) {
 }
 
 func (f *maxFolder) result() Value {
+	if f.max == nil {
+		return vUndefined
+	}
 	return f.max
 }
 
@@ -661,6 +965,9 @@ func (f *avgFolder) update(value *Number) {
 }
 
 func (f *avgFolder) result() Value {
+	if f.count == 0 {
+		return vUndefined
+	}
 	return f.sum.Div(NewNumberFromInt(f.count), f.round.mode, f.round.scale)
 }
 
@@ -674,6 +981,276 @@ func rAvg(args *fnArgs) (Value, error) {
 	}, args, 1)
 }
 
+func rSort(args *fnArgs) (Value, error) {
+	if err := args.checkArgsNum(1); err != nil {
+		return nil, err
+	}
+	slice, ok, err := args.getSlice(0)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	return sortSlice(slice, func(value Value) (Value, error) {
+		return value, nil
+	})
+}
+
+func rSortBy(args *fnArgs) (Value, error) {
+	if err := args.checkArgsNum(2); err != nil {
+		return nil, err
+	}
+	slice, ok, err := args.getSlice(0)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	arg, err := args.get(1)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := arg.(*Text)
+	if !ok {
+		return nil, fmt.Errorf("argument #2 expected to be text")
+	}
+	path := tSelector(strings.Split(name.value, "."))
+	elementType := slice.typ.elementType
+	if _, ok := path.selectType(elementType); !ok {
+		return nil, fmt.Errorf("unknown field %s", path)
+	}
+	return sortSlice(slice, func(value Value) (Value, error) {
+		return path.selectFrom(elementType, value)
+	})
+}
+
+// sortSlice stably sorts the elements of `slice` by the key of each element.
+// Keys must all be numbers, all be texts, or all be bools, and the sorted
+// slice is undefined if any key is undefined.
+func sortSlice(slice *Slice, key func(value Value) (Value, error)) (Value, error) {
+	type keyed struct {
+		key, value Value
+	}
+	elements := make([]keyed, len(slice.elements))
+	for i, element := range slice.elements {
+		k, err := key(element.value)
+		if err != nil {
+			return nil, err
+		}
+		switch k.(type) {
+		case *Undefined:
+			return vUndefined, nil
+		case *Number, *Text, *Bool:
+		default:
+			return nil, fmt.Errorf("cannot sort by %s", k.Type())
+		}
+		elements[i] = keyed{k, element.value}
+	}
+	sort.SliceStable(elements, func(i, j int) bool {
+		return valueLessThan(elements[i].key, elements[j].key)
+	})
+	values := make([]Value, len(elements))
+	for i, element := range elements {
+		values[i] = element.value
+	}
+	return newSlice(slice.typ, values...), nil
+}
+
+// valueLessThan orders numbers numerically, texts lexicographically, and
+// false before true.
+func valueLessThan(left, right Value) bool {
+	switch l := left.(type) {
+	case *Number:
+		return l.LessThan(right.(*Number))
+	case *Text:
+		return l.value < right.(*Text).value
+	case *Bool:
+		return !l.value && right.(*Bool).value
+	}
+	return false
+}
+
+func rDistinct(args *fnArgs) (Value, error) {
+	if err := args.checkArgsNum(1); err != nil {
+		return nil, err
+	}
+	slice, ok, err := args.getSlice(0)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	var values []Value
+	for _, element := range slice.elements {
+		seen := false
+		for _, value := range values {
+			if valuesEqual(value, element.value) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			values = append(values, element.value)
+		}
+	}
+	return newSlice(slice.typ, values...), nil
+}
+
+func rReverse(args *fnArgs) (Value, error) {
+	if err := args.checkArgsNum(1); err != nil {
+		return nil, err
+	}
+	slice, ok, err := args.getSlice(0)
+	if err != nil || !ok {
+		return vUndefined, err
+	}
+	values := make([]Value, len(slice.elements))
+	for i, element := range slice.elements {
+		values[len(values)-1-i] = element.value
+	}
+	return newSlice(slice.typ, values...), nil
+}
+
+// numbersFolder collects all numbers, and sorts them, for functions which
+// need all numbers at once, such as `median`.
+type numbersFolder struct {
+	numbers []*Number
+	finish  func(sorted []*Number) Value
+}
+
+func (f *numbersFolder) update(value *Number) {
+	f.numbers = append(f.numbers, value)
+}
+
+func (f *numbersFolder) result() Value {
+	if len(f.numbers) == 0 {
+		return vUndefined
+	}
+	sort.SliceStable(f.numbers, func(i, j int) bool {
+		return f.numbers[i].LessThan(f.numbers[j])
+	})
+	return f.finish(f.numbers)
+}
+
+// percentileOf computes the `p` percentile of `sorted` numbers, interpolating
+// linearly between the closest ranks.
+func percentileOf(sorted []*Number, p *Number, round *tRound) *Number {
+	h := NewNumberFromInt(len(sorted) - 1).Mult(p)
+	lower := h.Round(ModeDown, 0)
+	result := sorted[lower.value]
+	if frac := h.Minus(lower); frac.value != 0 {
+		result = result.Plus(frac.Mult(sorted[lower.value+1].Minus(result)))
+	}
+	return result.Round(round.mode, round.scale)
+}
+
+var vHalf = &Number{5, &NumberType{1}}
+
+func rMedian(args *fnArgs) (Value, error) {
+	if args.round == nil {
+		return nil, fmt.Errorf("missing rounding mode")
+	}
+	return rFoldNumbers(&numbersFolder{
+		finish: func(sorted []*Number) Value {
+			return percentileOf(sorted, vHalf, args.round)
+		},
+	}, args, 1)
+}
+
+func rPercentile(args *fnArgs) (Value, error) {
+	if args.round == nil {
+		return nil, fmt.Errorf("missing rounding mode")
+	}
+	if err := args.checkArgsNum(2); err != nil {
+		return nil, err
+	}
+	values, err := args.get(0)
+	if err != nil {
+		return nil, err
+	}
+	arg, err := args.get(1)
+	if err != nil {
+		return nil, err
+	}
+	var p *Number
+	switch v := arg.(type) {
+	case *Undefined:
+		return vUndefined, nil
+	case *Number:
+		if v.LessThan(vZero) || v.GreaterThan(NewNumberFromInt(1)) {
+			return nil, fmt.Errorf("argument #2 expected to be between 0 and 1, was %s", v)
+		}
+		p = v
+	default:
+		return nil, fmt.Errorf("argument #2 expected to be number")
+	}
+	return rFoldNumbers(&numbersFolder{
+		finish: func(sorted []*Number) Value {
+			return percentileOf(sorted, p, args.round)
+		},
+	}, newFnArgs(args.ws, args.round, []Value{values}), 1)
+}
+
+// stddevFolder computes the population standard deviation, exactly up to the
+// final rounding, as sqrt(n * sum(x^2) - sum(x)^2) / n.
+type stddevFolder struct {
+	numbers []*Number
+	round   *tRound
+}
+
+func (f *stddevFolder) update(value *Number) {
+	f.numbers = append(f.numbers, value)
+}
+
+func (f *stddevFolder) result() Value {
+	if len(f.numbers) == 0 {
+		return vUndefined
+	}
+
+	// all numbers at the same scale
+	scale := 0
+	for _, number := range f.numbers {
+		if scale < number.typ.scale {
+			scale = number.typ.scale
+		}
+	}
+	var (
+		n     = big.NewInt(int64(len(f.numbers)))
+		sum   = new(big.Int)
+		sumSq = new(big.Int)
+	)
+	for _, number := range f.numbers {
+		x := big.NewInt(number.scaleUp(scale))
+		sum.Add(sum, x)
+		sumSq.Add(sumSq, new(big.Int).Mul(x, x))
+	}
+
+	// with a = (n * sum(x^2) - sum(x)^2) * 10^(2 * round.scale), and
+	// d = n * 10^scale, the result times 10^round.scale is sqrt(a) / d
+	a := new(big.Int).Mul(n, sumSq)
+	a.Sub(a, new(big.Int).Mul(sum, sum))
+	a.Mul(a, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(2*f.round.scale)), nil))
+	d := new(big.Int).Mul(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+
+	var q *big.Int
+	switch f.round.mode {
+	case ModeUp:
+		root, r := new(big.Int).Sqrt(a), new(big.Int)
+		q, _ = new(big.Int).QuoRem(root, d, r)
+		if r.Sign() != 0 || new(big.Int).Mul(root, root).Cmp(a) != 0 {
+			q.Add(q, big.NewInt(1))
+		}
+	case ModeHalf:
+		// floor((sqrt(4a) + d) / 2d)
+		root := new(big.Int).Sqrt(new(big.Int).Mul(a, big.NewInt(4)))
+		q = new(big.Int).Quo(root.Add(root, d), new(big.Int).Mul(d, big.NewInt(2)))
+	default:
+		q = new(big.Int).Quo(new(big.Int).Sqrt(a), d)
+	}
+	return &Number{q.Int64(), &NumberType{f.round.scale}}
+}
+
+func rStddev(args *fnArgs) (Value, error) {
+	if args.round == nil {
+		return nil, fmt.Errorf("missing rounding mode")
+	}
+	return rFoldNumbers(&stddevFolder{round: args.round}, args, 1)
+}
+
 func (e *tCall) compute(ws *Worksheet) (Value, error) {
 	fn, ok := functions[e.name[0]]
 	if len(e.name) != 1 || !ok {
@@ -701,6 +1278,13 @@ func (e *ePlugin) selectors() []tSelector {
 }
 
 func (e *ePlugin) compute(ws *Worksheet) (Value, error) {
+	// When concurrency safe, the graph is locked, and plugins therefore get
+	// snapshots of worksheets which they can read without locking.
+	var snapshots map[*Worksheet]*Worksheet
+	if ws.graph != nil {
+		snapshots = make(map[*Worksheet]*Worksheet)
+	}
+
 	args := e.selectors()
 	values := make([]Value, len(args), len(args))
 	for i, arg := range args {
@@ -709,6 +1293,11 @@ func (e *ePlugin) compute(ws *Worksheet) (Value, error) {
 			// TODO(pascal): panic here, this should have failed earlier when binding Args
 			return nil, err
 		}
+		if snapshots != nil {
+			if snapValue, ok := snapshotValue(value, snapshots); ok {
+				value = snapValue
+			}
+		}
 		values[i] = value
 	}
 	return e.computedBy.Compute(values...), nil
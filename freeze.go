@@ -0,0 +1,119 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+)
+
+// Freeze makes the worksheet read-only. Any edit which would modify it fails,
+// be it an edit of the worksheet itself, an edit of another worksheet
+// requiring computed fields of this worksheet to be recomputed, or adding it
+// to, or removing it from, a slice or ref of another worksheet.
+func (ws *Worksheet) Freeze() {
+	defer ws.lock()()
+	ws.frozen = true
+}
+
+// IsFrozen indicates whether the worksheet is read-only.
+func (ws *Worksheet) IsFrozen() bool {
+	defer ws.rlock()()
+	return ws.frozen
+}
+
+// Snapshot returns a frozen view of the worksheet as it currently is, along
+// with the worksheets it references, directly or transitively. The original
+// worksheets can continue to be edited, and the snapshot is safe to read
+// concurrently from other goroutines.
+//
+// Taking a snapshot is cheap: since edits copy a worksheet's data before first
+// modifying it, the snapshot shares the data of the original worksheets, and
+// only copies data referencing other worksheets, to point to their snapshot.
+func (ws *Worksheet) Snapshot() *Worksheet {
+	defer ws.rlock()()
+	return ws.snapshot(make(map[*Worksheet]*Worksheet))
+}
+
+func (ws *Worksheet) snapshot(snapshots map[*Worksheet]*Worksheet) *Worksheet {
+	if snap, ok := snapshots[ws]; ok {
+		return snap
+	}
+	snap := &Worksheet{
+		def:     ws.def,
+		id:      ws.id,
+		orig:    make(map[int]Value),
+		data:    ws.data,
+		parents: make(parentsRefs),
+		frozen:  true,
+	}
+	snapshots[ws] = snap
+
+	var data map[int]Value
+	for index, value := range ws.data {
+		if snapValue, ok := snapshotValue(value, snapshots); ok {
+			if data == nil {
+				data = make(map[int]Value, len(ws.data))
+				for index, value := range ws.data {
+					data[index] = value
+				}
+			}
+			data[index] = snapValue
+		}
+	}
+	if data != nil {
+		snap.data = data
+	}
+	return snap
+}
+
+// snapshotValue snapshots the worksheets referenced by the value, if any.
+func snapshotValue(value Value, snapshots map[*Worksheet]*Worksheet) (Value, bool) {
+	switch v := value.(type) {
+	case *Worksheet:
+		return v.snapshot(snapshots), true
+	case *Slice:
+		var elements []sliceElement
+		for i, element := range v.elements {
+			snapValue, ok := snapshotValue(element.value, snapshots)
+			if !ok {
+				continue
+			}
+			if elements == nil {
+				elements = make([]sliceElement, len(v.elements))
+				copy(elements, v.elements)
+			}
+			elements[i].value = snapValue
+		}
+		if elements == nil {
+			return nil, false
+		}
+		return &Slice{
+			id:       v.id,
+			lastRank: v.lastRank,
+			typ:      v.typ,
+			elements: elements,
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// checkFrozen verifies that the transaction did not modify frozen worksheets.
+func (tx *editTx) checkFrozen() error {
+	for _, ws := range tx.order {
+		if _, ok := tx.saved[ws]; ok && ws.frozen {
+			return fmt.Errorf("cannot edit frozen worksheet %s", ws.def.name)
+		}
+	}
+	return nil
+}
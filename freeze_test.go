@@ -0,0 +1,136 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (s *Zuite) TestFreeze_mutatorsFail() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", member)
+
+	member.EnableHistory()
+	member.MustSet("bonus", MustNewValue("20"))
+	member.Freeze()
+	household.Freeze()
+	require.True(s.T(), member.IsFrozen())
+
+	errs := []error{
+		member.Set("income", MustNewValue("200")),
+		member.Unset("income"),
+		member.Edit(func(e *Edit) error {
+			e.Set("name", NewText("Alice"))
+			return nil
+		}),
+		member.Undo(),
+		household.Append("members", defs.MustNewWorksheet("member")),
+		household.Del("members", 0),
+	}
+	require.EqualError(s.T(), errs[0], "cannot edit frozen worksheet member")
+	require.EqualError(s.T(), errs[1], "cannot edit frozen worksheet member")
+	require.EqualError(s.T(), errs[2], "cannot edit frozen worksheet member")
+	require.EqualError(s.T(), errs[3], "cannot edit frozen worksheet member")
+	require.EqualError(s.T(), errs[4], "cannot edit frozen worksheet household")
+	require.EqualError(s.T(), errs[5], "cannot edit frozen worksheet household")
+
+	require.Equal(s.T(), "100", member.MustGet("income").String())
+	require.Equal(s.T(), "120", member.MustGet("total").String())
+	require.Equal(s.T(), []Value{member}, household.MustGetSlice("members"))
+}
+
+func (s *Zuite) TestFreeze_editsReachingFrozenWorksheetsFail() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", member)
+	household.Freeze()
+
+	// Recomputing the household's total income is an edit of the household.
+	err := member.Set("income", MustNewValue("200"))
+	require.EqualError(s.T(), err, "cannot edit frozen worksheet household")
+	require.Equal(s.T(), "100", member.MustGet("income").String())
+	require.Equal(s.T(), "100", household.MustGet("total_income").String())
+
+	// Fields the household does not depend on can be edited.
+	member.MustSet("name", NewText("Alice"))
+
+	// Frozen worksheets cannot be added to slices either.
+	other := defs.MustNewWorksheet("household")
+	err = other.Append("members", member.Snapshot())
+	require.EqualError(s.T(), err, "cannot edit frozen worksheet member")
+	require.Empty(s.T(), other.MustGetSlice("members"))
+}
+
+func (s *Zuite) TestSnapshot_readOnlyView() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", member)
+
+	snapshot := household.Snapshot()
+	require.True(s.T(), snapshot.IsFrozen())
+	require.False(s.T(), household.IsFrozen())
+	require.Equal(s.T(), household.Id(), snapshot.Id())
+
+	member.MustSet("income", MustNewValue("200"))
+	household.MustAppend("members", defs.MustNewWorksheet("member"))
+
+	members := snapshot.MustGetSlice("members")
+	require.Len(s.T(), members, 1)
+	snapMember := members[0].(*Worksheet)
+	require.True(s.T(), snapMember.IsFrozen())
+	require.Equal(s.T(), member.Id(), snapMember.Id())
+	require.Equal(s.T(), "100", snapMember.MustGet("income").String())
+	require.Equal(s.T(), "100", snapshot.MustGet("total_income").String())
+
+	require.EqualError(s.T(), snapMember.Set("income", MustNewValue("300")), "cannot edit frozen worksheet member")
+	require.Equal(s.T(), "200", member.MustGet("income").String())
+}
+
+func (s *Zuite) TestSnapshot_concurrentReads() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", member)
+
+	snapshot := household.Snapshot()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				members := snapshot.MustGetSlice("members")
+				income := members[0].(*Worksheet).MustGet("income")
+				if !income.Equal(MustNewValue("100")) || !snapshot.MustGet("total_income").Equal(MustNewValue("100")) {
+					panic("snapshot changed")
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		member.MustSet("income", NewNumberFromInt(200+j))
+		household.MustAppend("members", defs.MustNewWorksheet("member"))
+	}
+	wg.Wait()
+}
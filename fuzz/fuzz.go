@@ -21,9 +21,12 @@ import (
 )
 
 func Fuzz(data []byte) int {
-	_, err := worksheets.NewDefinitions(strings.NewReader(string(data)))
+	defs, err := worksheets.NewDefinitions(strings.NewReader(string(data)))
 	if err != nil {
 		return 1
 	}
+	if err := worksheets.CheckRoundTrip(defs); err != nil {
+		panic(err)
+	}
 	return 0
 }
@@ -0,0 +1,183 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+)
+
+// HistoryEntry records an edit of a worksheet.
+type HistoryEntry struct {
+	// Changes lists the changes made by the edit to the input fields of the
+	// worksheet. Reverting these changes undoes the edit.
+	Changes []Change
+
+	// Undone indicates whether the edit was undone, and can be redone.
+	Undone bool
+}
+
+type history struct {
+	done, undone []HistoryEntry
+}
+
+// EnableHistory starts recording the edits of this worksheet, such that they
+// can be undone, and redone. Only edits applied to this worksheet are
+// recorded, and not edits of other worksheets, even if they update this
+// worksheet's computed fields.
+func (ws *Worksheet) EnableHistory() {
+	defer ws.lock()()
+	if ws.history == nil {
+		ws.history = &history{}
+	}
+}
+
+// History returns the edits recorded, the oldest first, with edits which were
+// undone last and in the order in which they would be redone.
+func (ws *Worksheet) History() []HistoryEntry {
+	defer ws.rlock()()
+	if ws.history == nil {
+		return nil
+	}
+	entries := append([]HistoryEntry(nil), ws.history.done...)
+	for i := len(ws.history.undone) - 1; 0 <= i; i-- {
+		entries = append(entries, ws.history.undone[i])
+	}
+	return entries
+}
+
+// Undo reverts the last edit recorded, and recomputes computed fields
+// accordingly. Undoing is itself an edit, which editors may react to, and
+// which listeners are notified of.
+func (ws *Worksheet) Undo() error {
+	var entry HistoryEntry
+	return ws.applyEdit(nil, func() (*Edit, error) {
+		if ws.history == nil {
+			return nil, fmt.Errorf("history not enabled")
+		}
+		if len(ws.history.done) == 0 {
+			return nil, fmt.Errorf("nothing to undo")
+		}
+		entry = ws.history.done[len(ws.history.done)-1]
+		return entry.revert(false), nil
+	}, func(_ []Change) {
+		ws.history.done = ws.history.done[:len(ws.history.done)-1]
+		entry.Undone = true
+		ws.history.undone = append(ws.history.undone, entry)
+	})
+}
+
+// Redo re-applies the last edit undone.
+func (ws *Worksheet) Redo() error {
+	var entry HistoryEntry
+	return ws.applyEdit(nil, func() (*Edit, error) {
+		if ws.history == nil {
+			return nil, fmt.Errorf("history not enabled")
+		}
+		if len(ws.history.undone) == 0 {
+			return nil, fmt.Errorf("nothing to redo")
+		}
+		entry = ws.history.undone[len(ws.history.undone)-1]
+		return entry.revert(true), nil
+	}, func(_ []Change) {
+		ws.history.undone = ws.history.undone[:len(ws.history.undone)-1]
+		entry.Undone = false
+		ws.history.done = append(ws.history.done, entry)
+	})
+}
+
+// record records the changes to the input fields of `ws`, if any, as a new
+// entry, which clears edits which can be redone. A nil history records
+// nothing.
+func (h *history) record(ws *Worksheet, changes []Change) {
+	if h == nil {
+		return
+	}
+	var entry HistoryEntry
+	for _, change := range changes {
+		if change.Worksheet == ws && change.Field.computedBy == nil {
+			entry.Changes = append(entry.Changes, change)
+		}
+	}
+	if len(entry.Changes) == 0 {
+		return
+	}
+	h.done = append(h.done, entry)
+	h.undone = nil
+}
+
+// revert builds the edit reverting the entry's changes, i.e. restoring the
+// values before the changes, or after them when redoing.
+func (entry HistoryEntry) revert(redo bool) *Edit {
+	e := NewEdit()
+	for _, change := range entry.Changes {
+		value := change.Before
+		if redo {
+			value = change.After
+		}
+		switch v := value.(type) {
+		case *Slice:
+			e.ops = append(e.ops, editOp{kind: editRestore, name: change.Field.name, value: v})
+		case *Undefined:
+			e.Unset(change.Field.name)
+		default:
+			e.Set(change.Field.name, v)
+		}
+	}
+	return e
+}
+
+// restore restores the slice field to hold the elements of `target`, at the
+// same ranks, and updates parent pointers of the worksheets added, and
+// removed.
+func (ws *Worksheet) restore(tx *editTx, field *Field, target *Slice) error {
+	tx.touch(ws)
+	current, ok := ws.data[field.index].(*Slice)
+	if !ok {
+		current = newSlice(field.typ.(*SliceType))
+	}
+
+	// The restored slice keeps its identity, and never reuses ranks, hence
+	// the highest last rank. Elements are copied to avoid sharing their
+	// backing array with the target, which later appends could overwrite.
+	restored := &Slice{
+		id:       current.id,
+		typ:      current.typ,
+		lastRank: current.lastRank,
+		elements: make([]sliceElement, len(target.elements)),
+	}
+	if restored.lastRank < target.lastRank {
+		restored.lastRank = target.lastRank
+	}
+	copy(restored.elements, target.elements)
+	ws.data[field.index] = restored
+
+	// As in updateSlice, deleted worksheets may still be in the slice, e.g.
+	// if repeated.
+	diff := diffSlices(current, restored)
+	for _, element := range diff.added {
+		ws.handleDependentUpdates(tx, field, nil, element.value)
+	}
+	remaining := make(map[*Worksheet]bool)
+	for _, childWs := range extractChildWs(restored) {
+		remaining[childWs] = true
+	}
+	for _, element := range diff.deleted {
+		for _, childWs := range extractChildWs(element.value) {
+			if !remaining[childWs] {
+				ws.handleDependentUpdates(tx, field, childWs, nil)
+			}
+		}
+	}
+	tx.markDependents(ws, field)
+	return nil
+}
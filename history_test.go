@@ -0,0 +1,139 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (s *Zuite) TestHistory_undoRedo() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+
+	member.EnableHistory()
+	err := NewEdit().
+		Set("income", MustNewValue("200")).
+		Set("bonus", MustNewValue("20")).
+		Apply(member)
+	require.NoError(s.T(), err)
+	member.MustSet("name", NewText("Alice"))
+	require.Equal(s.T(), "220", member.MustGet("total").String())
+	require.Len(s.T(), member.History(), 2)
+
+	require.NoError(s.T(), member.Undo())
+	require.False(s.T(), member.MustIsSet("name"))
+
+	require.NoError(s.T(), member.Undo())
+	require.Equal(s.T(), "100", member.MustGet("income").String())
+	require.False(s.T(), member.MustIsSet("bonus"))
+	require.Equal(s.T(), "undefined", member.MustGet("total").String())
+	require.EqualError(s.T(), member.Undo(), "nothing to undo")
+
+	history := member.History()
+	require.Len(s.T(), history, 2)
+	require.True(s.T(), history[0].Undone)
+	require.Equal(s.T(), "income", history[0].Changes[0].Field.Name())
+	require.Equal(s.T(), "bonus", history[0].Changes[1].Field.Name())
+
+	require.NoError(s.T(), member.Redo())
+	require.Equal(s.T(), "220", member.MustGet("total").String())
+	require.False(s.T(), member.MustIsSet("name"))
+
+	// A new edit discards edits which could be redone.
+	member.MustSet("bonus", MustNewValue("30"))
+	require.EqualError(s.T(), member.Redo(), "nothing to redo")
+	require.Len(s.T(), member.History(), 2)
+
+	require.NoError(s.T(), member.Undo())
+	require.Equal(s.T(), "220", member.MustGet("total").String())
+}
+
+func (s *Zuite) TestHistory_slicesAndParents() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	alice := defs.MustNewWorksheet("member")
+	alice.MustSet("income", MustNewValue("100"))
+	bob := defs.MustNewWorksheet("member")
+	bob.MustSet("income", MustNewValue("50"))
+
+	household := defs.MustNewWorksheet("household")
+	household.EnableHistory()
+	household.MustAppend("members", alice)
+	household.MustAppend("members", bob)
+	household.MustDel("members", 0)
+	require.Equal(s.T(), []Value{bob}, household.MustGetSlice("members"))
+	require.Empty(s.T(), alice.parents["household"])
+
+	require.NoError(s.T(), household.Undo())
+	require.Equal(s.T(), []Value{alice, bob}, household.MustGetSlice("members"))
+	require.Equal(s.T(), "150", household.MustGet("total_income").String())
+	require.Len(s.T(), alice.parents["household"], 1)
+
+	require.NoError(s.T(), household.Undo())
+	require.Equal(s.T(), []Value{alice}, household.MustGetSlice("members"))
+	require.Equal(s.T(), "100", household.MustGet("total_income").String())
+	require.Empty(s.T(), bob.parents["household"][1])
+
+	// Parent pointers are restored: edits of children reach the household
+	// only when they are members.
+	bob.MustSet("income", MustNewValue("60"))
+	require.Equal(s.T(), "100", household.MustGet("total_income").String())
+
+	require.NoError(s.T(), household.Redo())
+	require.NoError(s.T(), household.Redo())
+	require.Equal(s.T(), []Value{bob}, household.MustGetSlice("members"))
+	require.Equal(s.T(), "60", household.MustGet("total_income").String())
+	alice.MustSet("income", MustNewValue("110"))
+	require.Equal(s.T(), "60", household.MustGet("total_income").String())
+
+	// Ranks are never reused after undoing appends.
+	require.NoError(s.T(), household.Undo())
+	require.NoError(s.T(), household.Undo())
+	household.MustAppend("members", bob)
+	_, slice, err := household.getSlice("members")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 3, slice.elements[1].rank)
+}
+
+func (s *Zuite) TestHistory_repeatedElements() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	alice := defs.MustNewWorksheet("member")
+	alice.MustSet("income", MustNewValue("100"))
+
+	household := defs.MustNewWorksheet("household")
+	household.EnableHistory()
+	household.MustAppend("members", alice)
+	household.MustAppend("members", alice)
+	require.Equal(s.T(), "200", household.MustGet("total_income").String())
+
+	// Undoing the second append deletes one element, but alice remains a
+	// member, and her edits must still reach the household.
+	require.NoError(s.T(), household.Undo())
+	require.Equal(s.T(), []Value{alice}, household.MustGetSlice("members"))
+	require.Len(s.T(), alice.parents["household"], 1)
+
+	alice.MustSet("income", MustNewValue("110"))
+	require.Equal(s.T(), "110", household.MustGet("total_income").String())
+}
+
+func (s *Zuite) TestHistory_notEnabled() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	member.MustSet("income", MustNewValue("100"))
+
+	require.Nil(s.T(), member.History())
+	require.EqualError(s.T(), member.Undo(), "history not enabled")
+	require.EqualError(s.T(), member.Redo(), "history not enabled")
+}
@@ -0,0 +1,122 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Impacts returns all computed fields which the field `path`, e.g.
+// `borrower.income`, flows into, be it directly or transitively, and across
+// worksheets. Fields are ordered by worksheet name, and then by field index.
+func (defs *Definitions) Impacts(path string) ([]*Field, error) {
+	parts := strings.SplitN(path, ".", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s: expected worksheet.field", path)
+	}
+	def, ok := defs.defs[parts[0]].(*Definition)
+	if !ok {
+		return nil, fmt.Errorf("unknown worksheet %s", parts[0])
+	}
+	field, ok := def.fieldsByName[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s", path)
+	}
+
+	var (
+		seen    = make(map[*Field]bool)
+		impacts []*Field
+		queue   = []*Field{field}
+	)
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range current.dependents {
+			if !seen[dependent] {
+				seen[dependent] = true
+				impacts = append(impacts, dependent)
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	sort.Slice(impacts, func(i, j int) bool {
+		if impacts[i].def.name != impacts[j].def.name {
+			return impacts[i].def.name < impacts[j].def.name
+		}
+		return impacts[i].index < impacts[j].index
+	})
+	return impacts, nil
+}
+
+// Impact is a computed field of a specific worksheet.
+type Impact struct {
+	Worksheet *Worksheet
+	Field     *Field
+}
+
+func (impact Impact) String() string {
+	return fmt.Sprintf("%s(%s).%s", impact.Worksheet.def.name, impact.Worksheet.Id(), impact.Field.name)
+}
+
+// Impacts returns all computed fields which would be recomputed by an edit of
+// the field `name` on this worksheet, be it on this worksheet or on parent
+// worksheets pointing to it, directly or transitively. Impacts are listed in
+// the order in which they are discovered, closest first.
+func (ws *Worksheet) Impacts(name string) ([]Impact, error) {
+	defer ws.rlock()()
+
+	field, ok := ws.def.fieldsByName[name]
+	if !ok {
+		return nil, &UnknownField{ws, name}
+	}
+
+	type key struct {
+		id    string
+		field *Field
+	}
+	var (
+		seen    = make(map[key]bool)
+		impacts []Impact
+		queue   = []Impact{{ws, field}}
+	)
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		// Same dependents as those recomputed in an edit, ordered by field,
+		// and then by worksheet id.
+		dependents := current.Worksheet.dependents(current.Field)
+		position := make(map[*Field]int)
+		for i, dependentField := range current.Field.Dependents() {
+			position[dependentField] = i
+		}
+		sort.Slice(dependents, func(i, j int) bool {
+			if dependents[i].field != dependents[j].field {
+				return position[dependents[i].field] < position[dependents[j].field]
+			}
+			return dependents[i].ws.Id() < dependents[j].ws.Id()
+		})
+		for _, dependent := range dependents {
+			k := key{dependent.ws.Id(), dependent.field}
+			if !seen[k] {
+				seen[k] = true
+				impact := Impact{dependent.ws, dependent.field}
+				impacts = append(impacts, impact)
+				queue = append(queue, impact)
+			}
+		}
+	}
+	return impacts, nil
+}
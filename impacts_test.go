@@ -0,0 +1,143 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+func (s *Zuite) TestDefinitions_Impacts() {
+	defs := MustNewDefinitions(strings.NewReader(defsForExplain))
+
+	cases := map[string][]string{
+		"borrower.income": {
+			"mortgage.total_income",
+			"mortgage.can_take_mortgage",
+			"mortgage.label",
+		},
+		"property.name": {
+			"mortgage.label",
+		},
+		"mortgage.can_take_mortgage": {
+			"mortgage.label",
+		},
+		"mortgage.label": nil,
+	}
+	for path, expected := range cases {
+		impacts, err := defs.Impacts(path)
+		require.NoError(s.T(), err, path)
+
+		var actual []string
+		for _, field := range impacts {
+			actual = append(actual, field.qualifiedName())
+		}
+		require.Equal(s.T(), expected, actual, path)
+	}
+}
+
+func (s *Zuite) TestDefinitions_ImpactsErrors() {
+	defs := MustNewDefinitions(strings.NewReader(defsForExplain))
+
+	cases := map[string]string{
+		"income":              "income: expected worksheet.field",
+		"lender.income":       "unknown worksheet lender",
+		"borrower.not_here":   "unknown field borrower.not_here",
+		"borrower.income.foo": "unknown field borrower.income.foo",
+	}
+	for path, msg := range cases {
+		_, err := defs.Impacts(path)
+		require.EqualError(s.T(), err, msg, path)
+	}
+}
+
+func (s *Zuite) TestWorksheet_Impacts() {
+	defs := MustNewDefinitions(strings.NewReader(defsForExplain))
+
+	borrower := defs.MustNewWorksheet("borrower")
+	mortgage1 := defs.MustNewWorksheet("mortgage")
+	mortgage1.MustAppend("borrowers", borrower)
+	mortgage2 := defs.MustNewWorksheet("mortgage")
+	mortgage2.MustAppend("borrowers", borrower)
+	unrelated := defs.MustNewWorksheet("mortgage")
+	unrelated.MustAppend("borrowers", defs.MustNewWorksheet("borrower"))
+
+	impacts, err := borrower.Impacts("income")
+	require.NoError(s.T(), err)
+
+	var actual []string
+	for _, impact := range impacts {
+		actual = append(actual, impact.String())
+	}
+
+	var expected []string
+	for _, mortgage := range []*Worksheet{mortgage1, mortgage2} {
+		for _, name := range []string{"total_income", "can_take_mortgage", "label"} {
+			expected = append(expected, "mortgage("+mortgage.Id()+")."+name)
+		}
+	}
+	sort.Strings(expected)
+	sort.Strings(actual)
+	require.Equal(s.T(), expected, actual)
+
+	// Once the borrower is removed, only one mortgage is impacted.
+	mortgage2.MustDel("borrowers", 0)
+	impacts, err = borrower.Impacts("income")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []Impact{
+		{mortgage1, mortgage1.def.fieldsByName["total_income"]},
+		{mortgage1, mortgage1.def.fieldsByName["can_take_mortgage"]},
+		{mortgage1, mortgage1.def.fieldsByName["label"]},
+	}, impacts)
+
+	// A field with no dependents.
+	impacts, err = mortgage1.Impacts("label")
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), impacts)
+
+	_, err = borrower.Impacts("not_here")
+	require.EqualError(s.T(), err, "unknown field not_here")
+}
+
+func (s *Zuite) TestWorksheet_ImpactsThroughSeveralWorksheets() {
+	defs := MustNewDefinitions(strings.NewReader(defsForNestedSlices))
+	family := defs.MustNewWorksheet("family")
+	borrower := defs.MustNewWorksheet("borrower")
+	income := defs.MustNewWorksheet("income")
+	borrower.MustAppend("incomes", income)
+	family.MustAppend("borrowers", borrower)
+
+	// `borrowers.incomes.amount` goes from the family, through the borrower,
+	// to the income. And `grouped` selects `groups.amount`, also recomputed.
+	impacts, err := income.Impacts("amount")
+	require.NoError(s.T(), err)
+
+	var actual []string
+	for _, impact := range impacts {
+		actual = append(actual, impact.String())
+	}
+	var expected []string
+	for _, name := range []string{"amounts", "total", "lowest", "highest", "grouped"} {
+		expected = append(expected, "family("+family.Id()+")."+name)
+	}
+	sort.Strings(expected)
+	sort.Strings(actual)
+	require.Equal(s.T(), expected, actual)
+
+	// And these are indeed the fields recomputed by an edit.
+	income.MustSet("amount", NewNumberFromInt(5))
+	require.Equal(s.T(), "5", family.MustGet("total").String())
+	require.Equal(s.T(), "5", family.MustGet("highest").String())
+}
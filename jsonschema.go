@@ -0,0 +1,159 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema produces a JSON Schema (Draft 2020-12) describing the marshaled
+// form of the worksheet `name`, i.e. the graph of worksheets keyed by their ids
+// as produced by `MarshalJSON`.
+//
+// All worksheets reachable from `name`, as well as enums they use, are
+// described in `$defs`. Numbers are decimal strings whose pattern depends on
+// their scale, refs are represented by the id of the worksheet they point to,
+// and computed fields are marked `readOnly`.
+func (defs *Definitions) JSONSchema(name string) ([]byte, error) {
+	typ, ok := defs.defs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown worksheet %s", name)
+	}
+	def, ok := typ.(*Definition)
+	if !ok {
+		return nil, fmt.Errorf("unknown worksheet %s", name)
+	}
+
+	s := &schemaBuilder{
+		defs: make(map[string]interface{}),
+	}
+	s.worksheet(def)
+
+	var wsRefs []interface{}
+	for _, typ := range defs.Types() {
+		if _, ok := typ.(*Definition); !ok {
+			continue
+		}
+		if _, ok := s.defs[typ.Name()]; ok {
+			wsRefs = append(wsRefs, schemaRef(typ.Name()))
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"$schema":       jsonSchemaDraft,
+		"title":         name,
+		"type":          "object",
+		"minProperties": 1,
+		"additionalProperties": map[string]interface{}{
+			"anyOf": wsRefs,
+		},
+		"$defs": s.defs,
+	}, "", "  ")
+}
+
+type schemaBuilder struct {
+	defs map[string]interface{}
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"$ref": "#/$defs/" + name,
+	}
+}
+
+func (s *schemaBuilder) worksheet(def *Definition) {
+	if _, ok := s.defs[def.name]; ok {
+		return
+	}
+	// Placeholder to stop recursion on cyclic refs.
+	s.defs[def.name] = nil
+
+	properties := make(map[string]interface{})
+	for _, field := range def.fieldsByIndex {
+		schema := s.typ(field.typ)
+		if field.computedBy != nil {
+			schema["readOnly"] = true
+		}
+		properties[field.name] = schema
+	}
+
+	s.defs[def.name] = map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             []string{"id", "version"},
+		"additionalProperties": false,
+	}
+}
+
+func (s *schemaBuilder) typ(typ Type) map[string]interface{} {
+	switch t := typ.(type) {
+	case *UndefinedType:
+		return map[string]interface{}{
+			"type": "null",
+		}
+	case *TextType:
+		return map[string]interface{}{
+			"type": "string",
+		}
+	case *BoolType:
+		return map[string]interface{}{
+			"type": "boolean",
+		}
+	case *NumberType:
+		return map[string]interface{}{
+			"type":    "string",
+			"pattern": numberPattern(t.scale),
+		}
+	case *EnumType:
+		if _, ok := s.defs[t.name]; !ok {
+			s.defs[t.name] = map[string]interface{}{
+				"type": "string",
+				"enum": t.Elements(),
+			}
+		}
+		return schemaRef(t.name)
+	case *Definition:
+		s.worksheet(t)
+		return map[string]interface{}{
+			"type":        "string",
+			"description": fmt.Sprintf("id of a %s worksheet", t.name),
+		}
+	case *SliceType:
+		return map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"anyOf": []interface{}{
+					s.typ(t.elementType),
+					map[string]interface{}{
+						"type": "null",
+					},
+				},
+			},
+		}
+	default:
+		panic(fmt.Sprintf("unexpected type %s", typ))
+	}
+}
+
+// numberPattern returns the pattern matching the string representation of
+// numbers assignable to a `number[scale]`, which is to say numbers of a scale
+// lower or equal to `scale`.
+func numberPattern(scale int) string {
+	if scale == 0 {
+		return `^-?[0-9]+$`
+	}
+	return fmt.Sprintf(`^-?[0-9]+(\.[0-9]{1,%d})?$`, scale)
+}
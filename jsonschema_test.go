@@ -0,0 +1,145 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *Zuite) TestJSONSchema() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type yes_or_no enum {
+		"yes",
+		"no",
+	}
+
+	type borrower worksheet {
+		1:name     text
+		2:happy    bool
+		3:answer   yes_or_no
+		4:incomes  []number[2]
+		5:total    number[2] computed_by { return sum(incomes) }
+		6:employer employer
+	}
+
+	type employer worksheet {
+		1:name     text
+		2:borrower borrower
+	}
+
+	type unrelated worksheet {}`))
+
+	schema, err := defs.JSONSchema("borrower")
+	require.NoError(s.T(), err)
+
+	expected := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "borrower",
+		"type": "object",
+		"minProperties": 1,
+		"additionalProperties": {
+			"anyOf": [
+				{"$ref": "#/$defs/borrower"},
+				{"$ref": "#/$defs/employer"}
+			]
+		},
+		"$defs": {
+			"borrower": {
+				"type": "object",
+				"required": ["id", "version"],
+				"additionalProperties": false,
+				"properties": {
+					"id":       {"type": "string"},
+					"version":  {"type": "string", "pattern": "^-?[0-9]+$"},
+					"name":     {"type": "string"},
+					"happy":    {"type": "boolean"},
+					"answer":   {"$ref": "#/$defs/yes_or_no"},
+					"incomes":  {
+						"type": "array",
+						"items": {
+							"anyOf": [
+								{"type": "string", "pattern": "^-?[0-9]+(\\.[0-9]{1,2})?$"},
+								{"type": "null"}
+							]
+						}
+					},
+					"total":    {"type": "string", "pattern": "^-?[0-9]+(\\.[0-9]{1,2})?$", "readOnly": true},
+					"employer": {"type": "string", "description": "id of a employer worksheet"}
+				}
+			},
+			"employer": {
+				"type": "object",
+				"required": ["id", "version"],
+				"additionalProperties": false,
+				"properties": {
+					"id":       {"type": "string"},
+					"version":  {"type": "string", "pattern": "^-?[0-9]+$"},
+					"name":     {"type": "string"},
+					"borrower": {"type": "string", "description": "id of a borrower worksheet"}
+				}
+			},
+			"yes_or_no": {
+				"type": "string",
+				"enum": ["no", "yes"]
+			}
+		}
+	}`
+	require.JSONEq(s.T(), expected, string(schema))
+}
+
+func (s *Zuite) TestJSONSchema_unknownWorksheet() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type yes_or_no enum {
+		"yes",
+		"no",
+	}`))
+
+	_, err := defs.JSONSchema("not_here")
+	require.EqualError(s.T(), err, "unknown worksheet not_here")
+
+	_, err = defs.JSONSchema("yes_or_no")
+	require.EqualError(s.T(), err, "unknown worksheet yes_or_no")
+}
+
+func (s *Zuite) TestJSONSchema_numberPattern() {
+	cases := []struct {
+		scale   int
+		value   string
+		matches bool
+	}{
+		{0, "0", true},
+		{0, "-42", true},
+		{0, "4.2", false},
+		{2, "42", true},
+		{2, "-0.05", true},
+		{2, "4.2", true},
+		{2, "4.200", false},
+		{2, "4.", false},
+		{2, "", false},
+	}
+	for _, ex := range cases {
+		re := regexp.MustCompile(numberPattern(ex.scale))
+		assert.Equal(s.T(), ex.matches, re.MatchString(ex.value), "%s in number[%d]", ex.value, ex.scale)
+	}
+
+	// Marshaled numbers conform to the pattern of the field they're stored in.
+	for _, value := range []string{"0", "-1", "0.01", "-0.5", "123.45"} {
+		num := MustNewValue(value).(*Number)
+		re := regexp.MustCompile(numberPattern(2))
+		assert.True(s.T(), re.MatchString(num.String()), value)
+	}
+}
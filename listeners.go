@@ -0,0 +1,161 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+)
+
+// Change describes how a field of a worksheet changed as the result of an
+// edit.
+type Change struct {
+	Worksheet *Worksheet
+	Field     *Field
+
+	// Before and After are the values of the field before and after the
+	// edit. Unset fields are undefined.
+	Before, After Value
+
+	// Added and Removed are, for slice fields, the elements added to, and
+	// removed from the slice. Replacing an element shows as both.
+	Added, Removed []Value
+}
+
+func (change Change) String() string {
+	return fmt.Sprintf("%s(%s).%s: %s -> %s", change.Worksheet.def.name, change.Worksheet.Id(), change.Field.name, change.Before, change.After)
+}
+
+type listener struct {
+	// indexes holds the indexes of the fields listened to, or nil for all
+	// fields.
+	indexes map[int]bool
+	fn      func(Change)
+}
+
+// Subscribe registers `fn` to be invoked whenever any of the fields named
+// changes, or whenever any field changes if no names are given. Listeners are
+// only notified of successful edits, and see the change of computed fields
+// alongside the change of the fields they are computed from.
+//
+// Listeners are invoked synchronously, once the edit is fully applied. Changes
+// are notified worksheet by worksheet, in the order in which the edit first
+// modified them, i.e. the edited worksheet first, and then worksheets whose
+// computed fields were updated as a result. Within a worksheet, changes are
+// notified by field index, and listeners in order of subscription.
+//
+// Listeners may edit worksheets. Such edits are separate edits, applied
+// immediately, and whose listeners are also notified immediately, i.e. before
+// listeners of the original edit which are yet to be notified. As a result,
+// the After value of a change may be stale by the time it is notified.
+func (ws *Worksheet) Subscribe(names []string, fn func(Change)) error {
+	var indexes map[int]bool
+	if len(names) != 0 {
+		indexes = make(map[int]bool)
+		for _, name := range names {
+			field, ok := ws.def.fieldsByName[name]
+			if !ok {
+				return &UnknownField{ws, name}
+			}
+			indexes[field.index] = true
+		}
+	}
+	defer ws.lock()()
+	ws.listeners = append(ws.listeners, listener{indexes, fn})
+	return nil
+}
+
+// changes lists the changes made to all worksheets touched by the
+// transaction, in the order in which the worksheets were first touched.
+func (tx *editTx) changes() []Change {
+	var changes []Change
+	for _, ws := range tx.order {
+		original := tx.originals[ws]
+		for _, field := range sortedFieldsByIndex(ws.def) {
+			before, hasBefore := original[field.index]
+			after, hasAfter := ws.data[field.index]
+			if !hasBefore && !hasAfter {
+				continue
+			}
+
+			change := Change{
+				Worksheet: ws,
+				Field:     field,
+				Before:    before,
+				After:     after,
+			}
+			if sliceType, ok := field.typ.(*SliceType); ok {
+				if !hasBefore {
+					change.Before = newSlice(sliceType)
+				}
+				if !hasAfter {
+					change.After = newSlice(sliceType)
+				}
+				diff := diffSlices(change.Before.(*Slice), change.After.(*Slice))
+				if len(diff.added) == 0 && len(diff.deleted) == 0 {
+					continue
+				}
+				for _, element := range diff.added {
+					change.Added = append(change.Added, element.value)
+				}
+				for _, element := range diff.deleted {
+					change.Removed = append(change.Removed, element.value)
+				}
+			} else {
+				if !hasBefore {
+					change.Before = vUndefined
+				}
+				if !hasAfter {
+					change.After = vUndefined
+				}
+				if change.Before.diffCompare(change.After) {
+					continue
+				}
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// notification pairs a change with the listeners to notify of it.
+type notification struct {
+	change    Change
+	listeners []func(Change)
+}
+
+// notificationsOf determines the listeners to notify of changes. Listeners
+// subscribing after this point are only notified of later changes.
+func notificationsOf(changes []Change) []notification {
+	var notifications []notification
+	for _, change := range changes {
+		var fns []func(Change)
+		for _, l := range change.Worksheet.listeners {
+			if l.indexes == nil || l.indexes[change.Field.index] {
+				fns = append(fns, l.fn)
+			}
+		}
+		if len(fns) != 0 {
+			notifications = append(notifications, notification{change, fns})
+		}
+	}
+	return notifications
+}
+
+// notify invokes listeners of all changes.
+func notify(notifications []notification) {
+	for _, n := range notifications {
+		for _, fn := range n.listeners {
+			fn(n.change)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordChanges subscribes to changes of `ws`, and records them as
+// `name: before -> after` in `log`.
+func recordChanges(log *[]string, ws *Worksheet, names ...string) error {
+	return ws.Subscribe(names, func(change Change) {
+		entry := fmt.Sprintf("%s: %s -> %s", change.Field.Name(), change.Before, change.After)
+		if _, ok := change.Field.Type().(*SliceType); ok {
+			entry = fmt.Sprintf("%s: +%d -%d", change.Field.Name(), len(change.Added), len(change.Removed))
+		}
+		*log = append(*log, entry)
+	})
+}
+
+func (s *Zuite) TestSubscribe_editsAndComputedFields() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+	household := defs.MustNewWorksheet("household")
+	household.MustAppend("members", member)
+
+	var log []string
+	require.NoError(s.T(), recordChanges(&log, member, "income", "total"))
+	require.NoError(s.T(), recordChanges(&log, household))
+
+	member.MustSet("name", NewText("Alice"))
+	require.Empty(s.T(), log)
+
+	err := NewEdit().
+		Set("income", MustNewValue("100")).
+		Set("bonus", MustNewValue("20")).
+		Apply(member)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{
+		"income: undefined -> 100",
+		"total: undefined -> 120",
+		"total_income: undefined -> 100",
+	}, log)
+
+	log = nil
+	member.MustUnset("income")
+	require.Equal(s.T(), []string{
+		"income: 100 -> undefined",
+		"total: 120 -> undefined",
+		"total_income: 100 -> undefined",
+	}, log)
+}
+
+func (s *Zuite) TestSubscribe_slices() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	alice := defs.MustNewWorksheet("member")
+	alice.MustSet("income", MustNewValue("100"))
+	bob := defs.MustNewWorksheet("member")
+	bob.MustSet("income", MustNewValue("50"))
+	household := defs.MustNewWorksheet("household")
+
+	var (
+		log     []string
+		changes []Change
+	)
+	require.NoError(s.T(), recordChanges(&log, household))
+	require.NoError(s.T(), household.Subscribe([]string{"members"}, func(change Change) {
+		changes = append(changes, change)
+	}))
+
+	household.MustAppend("members", alice)
+	household.MustAppend("members", bob)
+	require.Equal(s.T(), []string{
+		"members: +1 -0",
+		"total_income: 0 -> 100",
+		"members: +1 -0",
+		"total_income: 100 -> 150",
+	}, log)
+	require.Equal(s.T(), []Value{bob}, changes[1].Added)
+
+	log = nil
+	err := household.Edit(func(e *Edit) error {
+		e.Del("members", 0)
+		e.Append("members", alice)
+		return nil
+	})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"members: +1 -1"}, log)
+	require.Equal(s.T(), []Value{alice}, changes[2].Added)
+	require.Equal(s.T(), []Value{alice}, changes[2].Removed)
+}
+
+func (s *Zuite) TestSubscribe_notNotifiedOfFailedEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+
+	var log []string
+	require.NoError(s.T(), recordChanges(&log, member))
+
+	err := NewEdit().
+		Set("income", MustNewValue("100")).
+		Set("name", NewText("Mallory")).
+		Apply(member)
+	require.EqualError(s.T(), err, `"Mallory" not a valid value for constrained field name`)
+	require.Empty(s.T(), log)
+}
+
+func (s *Zuite) TestSubscribe_reentrantEdits() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+
+	var log []string
+	require.NoError(s.T(), member.Subscribe([]string{"income"}, func(change Change) {
+		if !member.MustIsSet("bonus") {
+			member.MustSet("bonus", MustNewValue("10"))
+		}
+	}))
+	require.NoError(s.T(), recordChanges(&log, member))
+
+	member.MustSet("income", MustNewValue("100"))
+	require.Equal(s.T(), []string{
+		// The listener's edit is notified first.
+		"bonus: undefined -> 10",
+		"total: undefined -> 110",
+		"income: undefined -> 100",
+	}, log)
+	require.Equal(s.T(), "110", member.MustGet("total").String())
+}
+
+func (s *Zuite) TestSubscribe_unknownField() {
+	defs := MustNewDefinitions(strings.NewReader(defsForEdit))
+	member := defs.MustNewWorksheet("member")
+
+	err := member.Subscribe([]string{"income", "salary"}, func(Change) {})
+	require.EqualError(s.T(), err, "unknown field salary")
+}
@@ -24,6 +24,8 @@ import (
 var _ json.Marshaler = &Worksheet{}
 
 func (ws *Worksheet) MarshalJSON() ([]byte, error) {
+	defer ws.rlock()()
+
 	m := &marshaler{
 		graph: make(map[string][]byte),
 	}
@@ -199,6 +201,8 @@ func (ctx *structScanCtx) setAllDestinations() {
 }
 
 func (ss *StructScanner) StructScan(ws *Worksheet, dest interface{}) error {
+	defer ws.rlock()()
+
 	v := reflect.ValueOf(dest)
 	if v.Type().Kind() != reflect.Ptr || v.Type().Elem().Kind() != reflect.Struct {
 		return fmt.Errorf("dest must be a *struct")
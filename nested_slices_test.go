@@ -0,0 +1,281 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"encoding/json"
+	"strings"
+
+	runner "github.com/homelight/dat/sqlx-runner"
+	"github.com/stretchr/testify/require"
+)
+
+var defsForNestedSlices = `
+type family worksheet {
+	1:borrowers []borrower
+	2:amounts   [][]number[2] computed_by { return borrowers.incomes.amount }
+	3:total     number[2]     computed_by { return sum(borrowers.incomes.amount) }
+	4:lowest    number[2]     computed_by { return min(borrowers.incomes.amount) }
+	5:highest   number[2]     computed_by { return max(borrowers.incomes.amount) }
+	6:groups    [][]income
+	7:grouped   number[2]     computed_by { return sum(groups.amount) }
+}
+
+type borrower worksheet {
+	1:incomes []income
+}
+
+type income worksheet {
+	1:amount number[2]
+}`
+
+func (s *Zuite) nestedSlice(ws *Worksheet, name string, elements ...Value) *Slice {
+	typ := ws.def.FieldByName(name).Type().(*SliceType).ElementType().(*SliceType)
+	slice, err := NewSlice(typ, elements...)
+	require.NoError(s.T(), err)
+	return slice
+}
+
+func (s *Zuite) TestNestedSlices_appendGetAndEdit() {
+	ws := s.defs.MustNewWorksheet("with_nested_slices")
+
+	row1 := s.nestedSlice(ws, "matrix", NewNumberFromInt(1), NewNumberFromFloat64(2.5))
+	row2 := s.nestedSlice(ws, "matrix")
+	ws.MustAppend("matrix", row1)
+	ws.MustAppend("matrix", row2)
+	require.Equal(s.T(), "[[1 2.5] []]", ws.data[42].String())
+
+	// nested slices are copied, and never shared
+	matrix := ws.MustGetSlice("matrix")
+	require.NotEqual(s.T(), row1.id, matrix[0].(*Slice).id)
+	require.Equal(s.T(), row1.Elements(), matrix[0].(*Slice).Elements())
+
+	ws.MustSetAt("matrix", 1, s.nestedSlice(ws, "matrix", NewNumberFromInt(3)))
+	ws.MustMove("matrix", 1, 0)
+	require.Equal(s.T(), "[[3] [1 2.5]]", ws.data[42].String())
+
+	// assignability is checked at all depths
+	_, err := NewSlice(ws.def.FieldByName("matrix").Type().(*SliceType).ElementType().(*SliceType), alice)
+	require.EqualError(s.T(), err, "cannot append value of type text to []number[2]")
+
+	texts, err := NewSlice(&SliceType{&TextType{}}, alice)
+	require.NoError(s.T(), err)
+	require.EqualError(s.T(), ws.Append("matrix", texts), "cannot append value of type []text to [][]number[2]")
+}
+
+func (s *Zuite) TestNestedSlices_selectors() {
+	defs := MustNewDefinitions(strings.NewReader(defsForNestedSlices))
+	family := defs.MustNewWorksheet("family")
+	alice := defs.MustNewWorksheet("borrower")
+	bob := defs.MustNewWorksheet("borrower")
+	salary := defs.MustNewWorksheet("income")
+	salary.MustSet("amount", MustNewValue("1000.50"))
+	bonus := defs.MustNewWorksheet("income")
+	bonus.MustSet("amount", MustNewValue("200"))
+	rent := defs.MustNewWorksheet("income")
+	rent.MustSet("amount", MustNewValue("750"))
+
+	// no borrowers, no amounts
+	require.Len(s.T(), family.MustGetSlice("amounts"), 0)
+	require.Equal(s.T(), "0", family.MustGet("total").String())
+	require.Equal(s.T(), "undefined", family.MustGet("lowest").String())
+	require.Equal(s.T(), "undefined", family.MustGet("highest").String())
+
+	alice.MustAppend("incomes", salary)
+	alice.MustAppend("incomes", bonus)
+	bob.MustAppend("incomes", rent)
+	family.MustAppend("borrowers", alice)
+	family.MustAppend("borrowers", bob)
+
+	amounts := family.MustGetSlice("amounts")
+	require.Len(s.T(), amounts, 2)
+	require.Equal(s.T(), "[1000.50 200]", amounts[0].String())
+	require.Equal(s.T(), "[750]", amounts[1].String())
+	require.Equal(s.T(), "1950.50", family.MustGet("total").String())
+	require.Equal(s.T(), "200", family.MustGet("lowest").String())
+	require.Equal(s.T(), "1000.50", family.MustGet("highest").String())
+
+	// edits of incomes cascade through both levels of slices
+	bonus.MustSet("amount", MustNewValue("2000"))
+	require.Equal(s.T(), "3750.50", family.MustGet("total").String())
+	require.Equal(s.T(), "2000", family.MustGet("highest").String())
+
+	bonus.MustUnset("amount")
+	require.Equal(s.T(), "undefined", family.MustGet("total").String())
+}
+
+func (s *Zuite) TestNestedSlices_aggregatesOfEmptySlices() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type family worksheet {
+		1:borrowers     []borrower
+		2:highest_first number[2] computed_by { return max(borrowers.incomes.amount, 0) }
+		3:highest_last  number[2] computed_by { return max(0, borrowers.incomes.amount) }
+		4:lowest_first  number[2] computed_by { return min(borrowers.incomes.amount, 5) }
+		5:lowest_last   number[2] computed_by { return min(5, borrowers.incomes.amount) }
+		6:grid          [][][]number[0]
+		7:grid_min      number[0] computed_by { return min(grid) }
+		8:grid_avg      number[2] computed_by { return avg(grid) round down 2 }
+		9:grid_median   number[2] computed_by { return median(grid) round down 2 }
+	}
+
+	type borrower worksheet {
+		1:incomes []income
+	}
+
+	type income worksheet {
+		1:amount number[2]
+	}`))
+	family := defs.MustNewWorksheet("family")
+	alice := defs.MustNewWorksheet("borrower")
+	bob := defs.MustNewWorksheet("borrower")
+	rent := defs.MustNewWorksheet("income")
+	rent.MustSet("amount", MustNewValue("750"))
+
+	// a borrower without incomes contributes an empty slice at depth 2
+	family.MustAppend("borrowers", alice)
+	require.Equal(s.T(), "0", family.MustGet("highest_first").String())
+	require.Equal(s.T(), "0", family.MustGet("highest_last").String())
+	require.Equal(s.T(), "5", family.MustGet("lowest_first").String())
+	require.Equal(s.T(), "5", family.MustGet("lowest_last").String())
+
+	bob.MustAppend("incomes", rent)
+	family.MustAppend("borrowers", bob)
+	require.Equal(s.T(), "750", family.MustGet("highest_first").String())
+	require.Equal(s.T(), "750", family.MustGet("highest_last").String())
+	require.Equal(s.T(), "5", family.MustGet("lowest_first").String())
+	require.Equal(s.T(), "5", family.MustGet("lowest_last").String())
+
+	// undefined elements still make aggregates undefined, in any position
+	rent.MustUnset("amount")
+	require.Equal(s.T(), "undefined", family.MustGet("highest_first").String())
+	require.Equal(s.T(), "undefined", family.MustGet("highest_last").String())
+
+	// grid = [[[]], [[4]]]
+	innerType := &SliceType{&NumberType{0}}
+	rowType := &SliceType{innerType}
+	row := func(elements ...Value) *Slice {
+		inner, err := NewSlice(innerType, elements...)
+		require.NoError(s.T(), err)
+		slice, err := NewSlice(rowType, inner)
+		require.NoError(s.T(), err)
+		return slice
+	}
+	family.MustAppend("grid", row())
+	require.Equal(s.T(), "undefined", family.MustGet("grid_min").String())
+	family.MustAppend("grid", row(NewNumberFromInt(4)))
+	require.Equal(s.T(), "4", family.MustGet("grid_min").String())
+	require.Equal(s.T(), "4.00", family.MustGet("grid_avg").String())
+	require.Equal(s.T(), "4.00", family.MustGet("grid_median").String())
+}
+
+func (s *Zuite) TestNestedSlices_ofWorksheets() {
+	defs := MustNewDefinitions(strings.NewReader(defsForNestedSlices))
+	family := defs.MustNewWorksheet("family")
+	salary := defs.MustNewWorksheet("income")
+	salary.MustSet("amount", MustNewValue("10"))
+	bonus := defs.MustNewWorksheet("income")
+	bonus.MustSet("amount", MustNewValue("5"))
+
+	group := s.nestedSlice(family, "groups", salary, bonus)
+	family.MustAppend("groups", group)
+	family.MustAppend("groups", s.nestedSlice(family, "groups", salary))
+	require.Equal(s.T(), "25", family.MustGet("grouped").String())
+
+	// parent pointers are kept through nested slices
+	require.Equal(s.T(), map[string]*Worksheet{family.Id(): family}, salary.parents["family"][6])
+	bonus.MustSet("amount", MustNewValue("6"))
+	require.Equal(s.T(), "26", family.MustGet("grouped").String())
+
+	family.MustDel("groups", 0)
+	require.Len(s.T(), bonus.parents["family"][6], 0)
+	require.Equal(s.T(), map[string]*Worksheet{family.Id(): family}, salary.parents["family"][6])
+	require.Equal(s.T(), "10", family.MustGet("grouped").String())
+}
+
+func (s *Zuite) TestNestedSlices_marshalingAndStructScan() {
+	ws := s.defs.MustNewWorksheet("with_nested_slices")
+	forciblySetId(ws, "the-id")
+	ws.MustAppend("matrix", s.nestedSlice(ws, "matrix", NewNumberFromInt(1), NewNumberFromFloat64(2.5)))
+	ws.MustAppend("matrix", s.nestedSlice(ws, "matrix"))
+	ws.MustAppend("matrix", s.nestedSlice(ws, "matrix", vUndefined))
+
+	expected := `{"the-id":{
+		"matrix": [["1", "2.5"], [], [null]],
+		"id": "the-id",
+		"version":"1"
+	}}`
+	actual, err := json.Marshal(ws)
+	require.NoError(s.T(), err)
+	s.requireSameJson(expected, actual)
+
+	var data struct {
+		Matrix [][]*float64 `ws:"matrix"`
+	}
+	require.NoError(s.T(), ws.StructScan(&data))
+	one, twoAndAHalf := float64(1), 2.5
+	require.Equal(s.T(), [][]*float64{{&one, &twoAndAHalf}, nil, {nil}}, data.Matrix)
+}
+
+func (s *Zuite) TestNestedSlices_saveLoadAndUpdate() {
+	var wsId string
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		ws := s.defs.MustNewWorksheet("with_nested_slices")
+		ws.MustAppend("matrix", s.nestedSlice(ws, "matrix", NewNumberFromInt(1), NewNumberFromInt(2)))
+		ws.MustAppend("matrix", s.nestedSlice(ws, "matrix", NewNumberFromInt(3)))
+		wsId = ws.Id()
+
+		session := s.store.Open(tx)
+		_, err := session.Save(ws)
+		return err
+	})
+
+	load := func() *Worksheet {
+		var ws *Worksheet
+		s.MustRunTransaction(func(tx *runner.Tx) error {
+			var err error
+			ws, err = s.store.Open(tx).Load(wsId)
+			return err
+		})
+		return ws
+	}
+	require.Equal(s.T(), "[[1 2] [3]]", load().data[42].String())
+
+	// moving keeps the elements of nested slices as stored
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		session := s.store.Open(tx)
+		ws, err := session.Load(wsId)
+		if err != nil {
+			return err
+		}
+		ws.MustMove("matrix", 1, 0)
+		ws.MustSetAt("matrix", 1, s.nestedSlice(ws, "matrix", NewNumberFromInt(4)))
+
+		_, err = session.Update(ws)
+		return err
+	})
+	require.Equal(s.T(), "[[3] [4]]", load().data[42].String())
+
+	// loading, and updating without edits, stores nothing
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		session := s.store.Open(tx)
+		ws, err := session.Load(wsId)
+		if err != nil {
+			return err
+		}
+		_, err = session.Update(ws)
+		return err
+	})
+	ws := load()
+	require.Equal(s.T(), 2, ws.Version())
+	require.Equal(s.T(), "[[3] [4]]", ws.data[42].String())
+}
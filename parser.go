@@ -62,6 +62,9 @@ var (
 	pOr                 = newTokenPattern("||", "\\|\\|")
 	pWorksheet          = newTokenPattern("worksheet", "worksheet")
 	pConstrainedBy      = newTokenPattern("constrained_by", "constrained_by")
+	pConstraints        = newTokenPattern("constraints", "constraints")
+	pMessage            = newTokenPattern("message", "message")
+	pRequired           = newTokenPattern("required", "required")
 	pComputedBy         = newTokenPattern("computed_by", "computed_by")
 	pExternal           = newTokenPattern("external", "external")
 	pUndefined          = newTokenPattern("undefined", "undefined")
@@ -161,6 +164,19 @@ func (p *parser) parseWorksheet(name string) (*Definition, error) {
 	}
 
 	for !p.peek(pRacco) {
+		if p.peek(pConstraints) {
+			constraints, err := p.parseConstraints()
+			if err != nil {
+				return nil, err
+			}
+			for _, constraint := range constraints {
+				if err := ws.addConstraint(constraint); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
 		field, err := p.parseField()
 		if err != nil {
 			return nil, err
@@ -212,6 +228,11 @@ func (p *parser) parseField() (*Field, error) {
 		typ:   typ,
 	}
 
+	if p.peek(pRequired) {
+		p.next()
+		f.required = true
+	}
+
 	choice, err := p.peekWithChoice([]*tokenPattern{
 		pComputedBy,
 		pConstrainedBy,
@@ -244,6 +265,10 @@ func (p *parser) parseField() (*Field, error) {
 			f.computedBy = expr
 		case "constrained":
 			f.constrainedBy = expr
+			f.constraintMessage, err = p.parseMessage()
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -251,6 +276,66 @@ func (p *parser) parseField() (*Field, error) {
 
 }
 
+// parseMessage parses the optional message of a constraint.
+//
+//  := message text
+//   | <empty>
+func (p *parser) parseMessage() (string, error) {
+	if !p.peek(pMessage) {
+		return "", nil
+	}
+	p.next()
+
+	text, err := p.nextAndCheck(pText)
+	if err != nil {
+		return "", err
+	}
+	message, err := strconv.Unquote(text)
+	if err != nil {
+		panic(fmt.Sprintf("unexpected: %s", err))
+	}
+	return message, nil
+}
+
+// parseConstraints
+//
+//  := constraints { (name : exp (message text)?)* }
+func (p *parser) parseConstraints() ([]*constraint, error) {
+	p.next()
+
+	_, err := p.nextAndCheck(pLacco)
+	if err != nil {
+		return nil, err
+	}
+
+	var constraints []*constraint
+	for !p.peek(pRacco) {
+		name, err := p.nextAndCheck(pName)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = p.nextAndCheck(pColon)
+		if err != nil {
+			return nil, err
+		}
+
+		expr, err := p.parseExpression(true)
+		if err != nil {
+			return nil, err
+		}
+
+		message, err := p.parseMessage()
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, &constraint{name, expr, message})
+	}
+	p.next()
+
+	return constraints, nil
+}
+
 func (p *parser) parseEnum(name string) (*EnumType, error) {
 	_, err := p.nextAndCheck(pLacco)
 	if err != nil {
@@ -427,6 +512,14 @@ func (p *parser) parseExpression(withOp bool) (expression, error) {
 				}
 			}
 
+			// The dependency of `sort_by` on the sort key of each element
+			// must be known when parsing, see `tCall.selectors`.
+			if len(selector) == 1 && selector[0] == "sort_by" && len(args) == 2 {
+				if _, ok := args[1].(*Text); !ok {
+					return nil, fmt.Errorf("sort_by: argument #2 expected to be a text literal")
+				}
+			}
+
 			first = &tCall{selector, args, round}
 		}
 
@@ -464,6 +557,13 @@ func (p *parser) parseExpression(withOp bool) (expression, error) {
 		panic(fmt.Sprintf("nextAndChoice returned '%s'", choice))
 	}
 
+	if choice == "ident" || choice == "paren" {
+		first, err = p.parsePostfix(first)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if !withOp {
 		return first, nil
 	}
@@ -544,6 +644,68 @@ func (p *parser) parseExpression(withOp bool) (expression, error) {
 	}
 }
 
+// parsePostfix
+//
+//  := expr
+//   | expr [ exp ]
+//   | expr [ exp? : exp? ]
+//   | expr . name
+func (p *parser) parsePostfix(expr expression) (expression, error) {
+	for {
+		switch {
+		case p.peek(pLbracket):
+			p.next()
+			var from, to expression
+			if !p.peek(pColon) {
+				var err error
+				from, err = p.parseExpression(true)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !p.peek(pColon) {
+				if _, err := p.nextAndCheck(pRbracket); err != nil {
+					return nil, err
+				}
+				expr = &tIndex{expr, from}
+				continue
+			}
+			p.next()
+			if !p.peek(pRbracket) {
+				var err error
+				to, err = p.parseExpression(true)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if _, err := p.nextAndCheck(pRbracket); err != nil {
+				return nil, err
+			}
+			expr = &tRange{expr, from, to}
+
+		case p.peek(pDot):
+			var path tSelector
+			for p.peek(pDot) {
+				p.next()
+				name, err := p.nextAndCheck(pName)
+				if err != nil {
+					return nil, err
+				}
+				path = append(path, name)
+			}
+			// Dependencies of computed fields are only known for members
+			// of selectors, or of elements thereof.
+			if _, ok := rootSelectors(expr); !ok {
+				return nil, fmt.Errorf("cannot select %s from %s", strings.Join(path, "."), printExpr(expr))
+			}
+			expr = &tMember{expr, path}
+
+		default:
+			return expr, nil
+		}
+	}
+}
+
 var opPrecedence = map[tOp]int{
 	opAnd:                1,
 	opOr:                 1,
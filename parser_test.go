@@ -134,6 +134,30 @@ func (s *Zuite) TestParser_parseExpression() {
 		`foo.bar`:     tSelector([]string{"foo", "bar"}),
 		`foo.bar.baz`: tSelector([]string{"foo", "bar", "baz"}),
 
+		// indexing & re-slicing
+		`foo[0]`: &tIndex{
+			tSelector([]string{"foo"}),
+			&Number{0, &NumberType{0}},
+		},
+		`foo[1:2]`: &tRange{
+			tSelector([]string{"foo"}),
+			&Number{1, &NumberType{0}},
+			&Number{2, &NumberType{0}},
+		},
+		`foo[:]`: &tRange{tSelector([]string{"foo"}), nil, nil},
+		`foo.bar[0].baz.qux`: &tMember{
+			&tIndex{
+				tSelector([]string{"foo", "bar"}),
+				&Number{0, &NumberType{0}},
+			},
+			tSelector([]string{"baz", "qux"}),
+		},
+		`(foo)[1:]`: &tRange{
+			tSelector([]string{"foo"}),
+			&Number{1, &NumberType{0}},
+			nil,
+		},
+
 		// calls
 		`len(something)`: &tCall{
 			tSelector([]string{"len"}),
@@ -425,6 +449,18 @@ func (s *Zuite) TestParser_parseExpressionErrors() {
 		`len(5,`: "expecting expression: `` did not match patterns",
 		`len(5!`: "expecting , or ): `!` did not match patterns",
 
+		`foo[0`:   "expected ], found <eof>",
+		`foo[0:1`: "expected ], found <eof>",
+		`foo[0].`: "expected name, found <eof>",
+
+		`sort_by(foo, bar)`:       "sort_by: argument #2 expected to be a text literal",
+		`sort_by(foo, "a" + "b")`: "sort_by: argument #2 expected to be a text literal",
+
+		// members of values whose dependencies are unknown
+		`len(foo).bar`:                 "cannot select bar from len(foo)",
+		`if(foo, bar.baz, len(qux)).x`: "cannot select x from if(foo, bar.baz, len(qux))",
+		`(a + b).c.d`:                  "cannot select c.d from a + b",
+
 		// will need to revisit when we implement mod operator
 		`4%0`:     `number must terminate with percent if present`,
 		`-1%_000`: `number must terminate with percent if present`,
@@ -0,0 +1,208 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ComputedBySource returns the source of the field's computed_by block, e.g.
+// `return a + b` or `external`, or the empty string if the field is not
+// computed.
+func (f *Field) ComputedBySource() string {
+	if f.computedBy == nil {
+		return ""
+	}
+	return printExpr(f.computedBy)
+}
+
+// printExpr renders an expression back into DSL text, which parses into the
+// same expression.
+func printExpr(expr expression) string {
+	var b bytes.Buffer
+	writeExpr(&b, expr)
+	return b.String()
+}
+
+func writeExpr(b *bytes.Buffer, expr expression) {
+	switch e := expr.(type) {
+	case *tExternal, *ePlugin:
+		b.WriteString("external")
+
+	case *tReturn:
+		b.WriteString("return ")
+		writeExpr(b, e.expr)
+
+	case tSelector:
+		b.WriteString(e.String())
+
+	case *tUnop:
+		b.WriteString(opSymbols[e.op])
+		// The operand of a unary operator extends as far right as possible,
+		// e.g. `!a && b` is `!(a && b)`, hence we use parenthesis to avoid
+		// any ambiguity.
+		writeOperand(b, e.expr, func(operand *tBinop) bool {
+			return true
+		})
+
+	case *tBinop:
+		// Operators are left associative, i.e. `a - b - c` is
+		// `(a - b) - c`. Roundings are attached to the closest operator, so
+		// parenthesis are needed to avoid roundings from being misplaced.
+		writeOperand(b, e.left, func(operand *tBinop) bool {
+			return e.round != nil || operand.round != nil || opPrecedence[operand.op] < opPrecedence[e.op]
+		})
+		fmt.Fprintf(b, " %s ", opSymbols[e.op])
+		writeOperand(b, e.right, func(operand *tBinop) bool {
+			return e.round != nil || operand.round != nil || opPrecedence[operand.op] <= opPrecedence[e.op]
+		})
+		writeRound(b, e.round)
+
+	case *tCall:
+		b.WriteString(e.name.String())
+		b.WriteRune('(')
+		for i, arg := range e.args {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			writeExpr(b, arg)
+		}
+		b.WriteRune(')')
+		writeRound(b, e.round)
+
+	case *tIndex:
+		writePostfixOperand(b, e.expr)
+		b.WriteRune('[')
+		writeExpr(b, e.index)
+		b.WriteRune(']')
+
+	case *tRange:
+		writePostfixOperand(b, e.expr)
+		b.WriteRune('[')
+		if e.from != nil {
+			writeExpr(b, e.from)
+		}
+		b.WriteRune(':')
+		if e.to != nil {
+			writeExpr(b, e.to)
+		}
+		b.WriteRune(']')
+
+	case *tMember:
+		writePostfixOperand(b, e.expr)
+		b.WriteRune('.')
+		b.WriteString(e.path.String())
+
+	case Value:
+		b.WriteString(e.String())
+
+	default:
+		panic(fmt.Sprintf("unexpected expression %T", expr))
+	}
+}
+
+// writePostfixOperand writes the operand of indexing, re-slicing, or
+// selecting, with parenthesis around anything but selectors, calls, and other
+// postfix expressions.
+func writePostfixOperand(b *bytes.Buffer, expr expression) {
+	switch expr.(type) {
+	case tSelector, *tCall, *tIndex, *tRange, *tMember:
+		writeExpr(b, expr)
+	default:
+		b.WriteRune('(')
+		writeExpr(b, expr)
+		b.WriteRune(')')
+	}
+}
+
+// writeOperand writes an operand of an operator, with parenthesis around
+// unary operators, and around binary operators when `needsParen` says so.
+func writeOperand(b *bytes.Buffer, expr expression, needsParen func(operand *tBinop) bool) {
+	paren := false
+	switch operand := expr.(type) {
+	case *tUnop:
+		paren = true
+	case *tBinop:
+		paren = needsParen(operand)
+	}
+	if paren {
+		b.WriteRune('(')
+	}
+	writeExpr(b, expr)
+	if paren {
+		b.WriteRune(')')
+	}
+}
+
+func writeRound(b *bytes.Buffer, round *tRound) {
+	if round != nil {
+		fmt.Fprintf(b, " round %s", round)
+	}
+}
+
+// checkRoundTrip verifies that printing an expression, and parsing it back,
+// yields the same expression.
+func checkRoundTrip(expr expression) error {
+	src := printExpr(expr)
+	p := newParser(strings.NewReader(src))
+
+	var (
+		parsed expression
+		err    error
+	)
+	if _, ok := expr.(*tReturn); ok {
+		parsed, err = p.parseStatement()
+	} else {
+		parsed, err = p.parseExpression(true)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", src, err)
+	}
+	if !p.isEof() {
+		return fmt.Errorf("%s: unexpected trailing input", src)
+	}
+	if !reflect.DeepEqual(expr, parsed) {
+		return fmt.Errorf("%s: parses into a different expression", src)
+	}
+	return nil
+}
+
+// checkRoundTripDefs checks the round trip of the expressions of all fields,
+// except those computed by plugins, and of all worksheet constraints.
+func checkRoundTripDefs(defs *Definitions) error {
+	for _, typ := range defs.defs {
+		def, ok := typ.(*Definition)
+		if !ok {
+			continue
+		}
+		for _, field := range def.fieldsByIndex {
+			for _, expr := range []expression{field.computedBy, field.constrainedBy} {
+				if _, ok := expr.(*ePlugin); expr == nil || ok {
+					continue
+				}
+				if err := checkRoundTrip(expr); err != nil {
+					return fmt.Errorf("%s.%s: %s", def.name, field.name, err)
+				}
+			}
+		}
+		for _, constraint := range def.constraints {
+			if err := checkRoundTrip(constraint.expr); err != nil {
+				return fmt.Errorf("%s constraint %s: %s", def.name, constraint.name, err)
+			}
+		}
+	}
+	return nil
+}
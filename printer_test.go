@@ -0,0 +1,145 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worksheets
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *Zuite) TestPrinter_roundTrip() {
+	cases := map[string]string{
+		// literals
+		`3`:         `3`,
+		`-5.12`:     `-5.12`,
+		`1_000`:     `1000`,
+		`5%`:        `0.05`,
+		`undefined`: `undefined`,
+		`"Alice"`:   `"Alice"`,
+		`"a \"b\""`: `"a \"b\""`,
+		`true`:      `true`,
+
+		// selectors & calls
+		`foo.bar.baz`:                   `foo.bar.baz`,
+		`first_of(undefined,6,"Alice")`: `first_of(undefined, 6, "Alice")`,
+		`foo.len()`:                     `foo.len()`,
+		`sum(len(foo), 8)`:              `sum(len(foo), 8)`,
+		`avg(foo) round down 2`:         `avg(foo) round down 2`,
+		`avg(foo) round up 1 + 3`:       `avg(foo) round up 1 + 3`,
+
+		// indexing & re-slicing
+		`foo[0]`:         `foo[0]`,
+		`foo[i + 1]`:     `foo[i + 1]`,
+		`foo[1:]`:        `foo[1:]`,
+		`foo[:2]`:        `foo[:2]`,
+		`foo[:]`:         `foo[:]`,
+		`foo[0].bar.baz`: `foo[0].bar.baz`,
+		`last(foo).bar`:  `last(foo).bar`,
+		`(a + b)[0]`:     `(a + b)[0]`,
+		`!foo[0]`:        `!foo[0]`,
+		`foo[1:][0]`:     `foo[1:][0]`,
+
+		// unary operators
+		`!foo`:          `!foo`,
+		`!foo && bar`:   `!(foo && bar)`,
+		`(!foo) && bar`: `(!foo) && bar`,
+		`bar || !foo`:   `bar || (!foo)`,
+		`!!foo`:         `!(!foo)`,
+
+		// binary operators
+		`3 + 4`:                    `3 + 4`,
+		`3 + 4 * 5`:                `3 + 4 * 5`,
+		`(3 + 4) * 5`:              `(3 + 4) * 5`,
+		`3 - 4 - 5`:                `3 - 4 - 5`,
+		`3 - (4 - 5)`:              `3 - (4 - 5)`,
+		`3 * (4 / 5 round down 0)`: `3 * (4 / 5 round down 0)`,
+		`a == b && c != d || e`:    `a == b && c != d || e`,
+		`a && (b || c)`:            `a && (b || c)`,
+		`a > b == (c <= d)`:        `a > b == (c <= d)`,
+		`x - -5`:                   `x - -5`,
+
+		// roundings
+		`5 round down 2`:                        `5 + 0 round down 2`,
+		`3 / 4 round half 2`:                    `3 / 4 round half 2`,
+		`1 + 3 / 4 round up 2`:                  `1 + (3 / 4 round up 2)`,
+		`1 + 3 + 4 round up 2`:                  `(1 + 3 round up 2) + 4`,
+		`(1 + 2) / 3 round down 0`:              `(1 + 2) / 3 round down 0`,
+		`a / b round down 0 * c / d round up 2`: `(a / b round down 0) * (c / d round up 2)`,
+	}
+	for input, expected := range cases {
+		s.T().Run(input, func(t *testing.T) {
+			p := newParser(strings.NewReader(input))
+			expr, err := p.parseExpression(true)
+			require.NoError(t, err)
+			require.True(t, p.isEof())
+
+			assert.Equal(t, expected, printExpr(expr))
+			assert.NoError(t, checkRoundTrip(expr))
+		})
+	}
+}
+
+func (s *Zuite) TestPrinter_ComputedBySource() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type simple worksheet {
+		1:age    number[0]
+		2:double number[0] computed_by { return age*2 }
+		3:name   text computed_by { external }
+	}`), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"simple": {
+				"name": sayAlice([]string{"age"}),
+			},
+		},
+	})
+	def := defs.defs["simple"].(*Definition)
+
+	require.Equal(s.T(), "", def.FieldByName("age").ComputedBySource())
+	require.Equal(s.T(), "return age * 2", def.FieldByName("double").ComputedBySource())
+	require.Equal(s.T(), "external", def.FieldByName("name").ComputedBySource())
+}
+
+func (s *Zuite) TestPrinter_roundTripDefinitions() {
+	sources := []string{
+		defs,
+		defsForExplain,
+		defsForDependencies,
+		defsForConstraints,
+	}
+	corpus, err := filepath.Glob("fuzz/corpus/*")
+	require.NoError(s.T(), err)
+	for _, filename := range corpus {
+		src, err := ioutil.ReadFile(filename)
+		require.NoError(s.T(), err)
+		sources = append(sources, string(src))
+	}
+
+	for _, src := range sources {
+		defs, err := NewDefinitions(strings.NewReader(src), Options{
+			Plugins: map[string]map[string]ComputedBy{
+				"game": {
+					"summary": sayAlice([]string{"winner"}),
+				},
+			},
+		})
+		if err != nil {
+			continue
+		}
+		require.NoError(s.T(), checkRoundTripDefs(defs))
+	}
+}
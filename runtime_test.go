@@ -195,6 +195,79 @@ func (s *Zuite) TestRuntime_parseAndEvalExpr() {
 		`avg(1, 1, 1, 1, 1, 1, 5) round half 1`: `1.6`,
 		`avg(1, 1, 1, 1, 1, 1, 5) round half 2`: `1.57`,
 		`avg(1, 1, 1, 1, 1, 1, 5) round half 3`: `1.571`,
+
+		// indexing & re-slicing
+		`slice_t[0]`:                `"Alice"`,
+		`slice_t[1]`:                `"Bob"`,
+		`slice_t[2]`:                `undefined`,
+		`slice_t[-1]`:               `undefined`,
+		`slice_t[undefined]`:        `undefined`,
+		`slice_nu[0]`:               `undefined`,
+		`slice_t[len(slice_t) - 1]`: `"Bob"`,
+		`(slice_t)[1]`:              `"Bob"`,
+		`slice(1, 2, 3)[1]`:         `2`,
+		`sum(slice_n0[1:])`:         `8`,
+		`sum(slice_n0[:2])`:         `5`,
+		`sum(slice_n0[1:2])`:        `3`,
+		`len(slice_n0[:])`:          `3`,
+		`len(slice_n0[3:])`:         `0`,
+		`slice_n0[1:][0]`:           `3`,
+		`slice_n0[2:1]`:             `undefined`,
+		`slice_n0[0:4]`:             `undefined`,
+
+		// last, index_of & contains
+		`last(slice_t)`:                 `"Bob"`,
+		`last(slice_n0[3:])`:            `undefined`,
+		`last(undefined)`:               `undefined`,
+		`index_of(slice_t, "Bob")`:      `1`,
+		`index_of(slice_t, "Carol")`:    `-1`,
+		`index_of(slice_n2, 3.33)`:      `1`,
+		`index_of(slice_nu, undefined)`: `0`,
+		`index_of(undefined, 1)`:        `undefined`,
+		`contains(slice_t, "Alice")`:    `true`,
+		`contains(slice_t, "Carol")`:    `false`,
+		`contains(slice_n0, 5.0)`:       `true`,
+		`contains(undefined, "Alice")`:  `undefined`,
+
+		// sort, sort_by, distinct & reverse
+		`sort(slice(3, 1, 2))[0]`:             `1`,
+		`last(sort(slice(3, 1, 2)))`:          `3`,
+		`sort(slice("b", "c", "a"))[0]`:       `"a"`,
+		`sort(slice(true, false))[0]`:         `false`,
+		`len(sort(slice_n0[3:]))`:             `0`,
+		`sort(slice_nu)`:                      `undefined`,
+		`sort(undefined)`:                     `undefined`,
+		`sort_by(undefined, "foo")`:           `undefined`,
+		`reverse(slice_t)[0]`:                 `"Bob"`,
+		`reverse(slice_n0)[2]`:                `2`,
+		`len(distinct(slice(1, 2, 1, 3, 2)))`: `3`,
+		`distinct(slice(2, 1, 2))[1]`:         `1`,
+		`len(distinct(slice_nu))`:             `3`,
+		`distinct(undefined)`:                 `undefined`,
+
+		// median, percentile & stddev
+		`median(slice(3, 1, 2)) round down 0`:                `2`,
+		`median(1, 2, 3, 4) round half 1`:                    `2.5`,
+		`median(1, 2, 3, 4) round down 0`:                    `2`,
+		`median(slice_n2) round down 1`:                      `3.3`,
+		`median(slice_nu) round down 0`:                      `undefined`,
+		`median(slice_n0[3:]) round down 0`:                  `undefined`,
+		`percentile(slice(1, 2, 3, 4, 5), 90%) round half 1`: `4.6`,
+		`percentile(slice(1, 2, 3, 4, 5), 90%) round down 0`: `4`,
+		`percentile(slice(5, 4, 3, 2, 1), 0) round down 0`:   `1`,
+		`percentile(slice(5, 4, 3, 2, 1), 1) round down 0`:   `5`,
+		`percentile(slice_n2, 50%) round half 2`:             `3.33`,
+		`percentile(undefined, 50%) round down 0`:            `undefined`,
+		`percentile(slice_n0, undefined) round down 0`:       `undefined`,
+		`stddev(2, 4, 4, 4, 5, 5, 7, 9) round down 0`:        `2`,
+		`stddev(1, 2) round half 2`:                          `0.50`,
+		`stddev(1, 2, 3) round down 3`:                       `0.816`,
+		`stddev(1, 2, 3) round up 3`:                         `0.817`,
+		`stddev(1, 2, 3) round half 4`:                       `0.8165`,
+		`stddev(slice(1.5, 2.5)) round up 1`:                 `0.5`,
+		`stddev(5) round down 1`:                             `0.0`,
+		`stddev(slice_nu) round down 0`:                      `undefined`,
+		`stddev(slice_n0[3:]) round down 0`:                  `undefined`,
 	}
 	for input, output := range cases {
 		// fixture
@@ -257,6 +330,29 @@ func (s *Zuite) TestRuntime_parseAndEvalExprExpectingFailure() {
 		`avg() round down 8`: `avg: at least 1 argument(s) expected but none found`,
 		`avg(1)`:             `avg: missing rounding mode`,
 
+		`text[0]`:                `cannot index text`,
+		`text[1:]`:               `cannot slice text`,
+		`slice_t[1.5]`:           `index must be number[0], was number[1]`,
+		`slice_t["a"]`:           `index must be number[0], was text`,
+		`slice_t[0].foo`:         `cannot select foo from text`,
+		`last()`:                 `last: 1 argument(s) expected but 0 found`,
+		`last(1)`:                `last: argument #1 expected to be slice`,
+		`index_of(slice_t)`:      `index_of: 2 argument(s) expected but 1 found`,
+		`contains("Alice", "A")`: `contains: argument #1 expected to be slice`,
+
+		`sort(slice_t, 1)`:                       `sort: 1 argument(s) expected but 2 found`,
+		`sort(1)`:                                `sort: argument #1 expected to be slice`,
+		`sort(slice(slice(1)))`:                  `sort: cannot sort by []number[0]`,
+		`sort_by(slice_t, "foo")`:                `sort_by: unknown field foo`,
+		`distinct(1)`:                            `distinct: argument #1 expected to be slice`,
+		`reverse("abc")`:                         `reverse: argument #1 expected to be slice`,
+		`median(1)`:                              `median: missing rounding mode`,
+		`median("one") round down 0`:             `median: encountered non-numerical argument`,
+		`percentile(slice_n0, 2) round down 0`:   `percentile: argument #2 expected to be between 0 and 1, was 2`,
+		`percentile(slice_n0, "a") round down 0`: `percentile: argument #2 expected to be number`,
+		`percentile(slice_n0)`:                   `percentile: missing rounding mode`,
+		`stddev(slice_t) round down 0`:           `stddev: encountered non-numerical argument`,
+
 		// TODO(pascal): would be much nicer to have the message
 		// `unable to round non-numerical value`.
 		`"no" round down 0`:        `op on non-number`,
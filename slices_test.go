@@ -15,6 +15,8 @@ package worksheets
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 
 	runner "github.com/homelight/dat/sqlx-runner"
 	"github.com/stretchr/testify/require"
@@ -186,6 +188,414 @@ func (s *Zuite) TestSliceOps() {
 	require.Equal(s.T(), sliceElement{3, bob}, slice6.elements[0])
 }
 
+func (s *Zuite) TestSliceOps_insert() {
+	slice := newSliceWithIdAndLastRank(&SliceType{&TextType{}}, "a-cool-id", 0)
+	for _, name := range []Value{alice, bob, carol} {
+		slice, _ = slice.doAppend(name)
+	}
+	dave := NewText("Dave")
+
+	cases := []struct {
+		index    int
+		expected []sliceElement
+		lastRank int
+	}{
+		// before first
+		{0, []sliceElement{{0, dave}, {1, alice}, {2, bob}, {3, carol}}, 3},
+		// contiguous ranks, preceding elements shifted down
+		{1, []sliceElement{{0, alice}, {1, dave}, {2, bob}, {3, carol}}, 3},
+		// contiguous ranks, following elements shifted up
+		{2, []sliceElement{{1, alice}, {2, bob}, {3, dave}, {4, carol}}, 4},
+		// after last
+		{3, []sliceElement{{1, alice}, {2, bob}, {3, carol}, {4, dave}}, 4},
+	}
+	for _, ex := range cases {
+		actual, err := slice.doInsert(ex.index, dave)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), ex.expected, actual.elements, "index %d", ex.index)
+		require.Equal(s.T(), ex.lastRank, actual.lastRank, "index %d", ex.index)
+		require.Equal(s.T(), slice.id, actual.id)
+	}
+
+	// gap in ranks
+	withGap, err := slice.doDel(1)
+	require.NoError(s.T(), err)
+	actual, err := withGap.doInsert(1, dave)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []sliceElement{{1, alice}, {2, dave}, {3, carol}}, actual.elements)
+
+	// original left untouched
+	require.Equal(s.T(), []sliceElement{{1, alice}, {2, bob}, {3, carol}}, slice.elements)
+
+	for _, index := range []int{-1, 4} {
+		_, err := slice.doInsert(index, dave)
+		require.EqualError(s.T(), err, "index out of range")
+	}
+
+	// repeated inserts at the front never yield negative ranks
+	front := slice
+	for i := 0; i < 5; i++ {
+		front, err = front.doInsert(0, NewText(strconv.Itoa(i)))
+		require.NoError(s.T(), err)
+	}
+	require.Equal(s.T(), []sliceElement{
+		{0, NewText("4")},
+		{1, NewText("3")},
+		{2, NewText("2")},
+		{3, NewText("1")},
+		{4, NewText("0")},
+		{5, alice},
+		{6, bob},
+		{7, carol},
+	}, front.elements)
+	require.Equal(s.T(), 7, front.lastRank)
+}
+
+func (s *Zuite) TestSliceOps_setAtMoveClear() {
+	slice := newSliceWithIdAndLastRank(&SliceType{&TextType{}}, "a-cool-id", 0)
+	for _, name := range []Value{alice, bob, carol} {
+		slice, _ = slice.doAppend(name)
+	}
+	dave := NewText("Dave")
+
+	actual, err := slice.doSetAt(1, dave)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []sliceElement{{1, alice}, {2, dave}, {3, carol}}, actual.elements)
+
+	actual, err = slice.doMove(0, 2)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []sliceElement{{2, bob}, {3, carol}, {4, alice}}, actual.elements)
+
+	actual, err = slice.doMove(2, 0)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []sliceElement{{0, carol}, {1, alice}, {2, bob}}, actual.elements)
+
+	actual, err = slice.doMove(1, 1)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), slice.elements, actual.elements)
+
+	actual = slice.doClear()
+	require.Len(s.T(), actual.elements, 0)
+	require.Equal(s.T(), 3, actual.lastRank)
+	require.Equal(s.T(), slice.id, actual.id)
+
+	_, err = slice.doSetAt(3, dave)
+	require.EqualError(s.T(), err, "index out of range")
+	_, err = slice.doMove(0, 3)
+	require.EqualError(s.T(), err, "index out of range")
+	_, err = slice.doMove(-1, 0)
+	require.EqualError(s.T(), err, "index out of range")
+}
+
+func (s *Zuite) TestSliceInsertSetAtMoveClear() {
+	ws := s.defs.MustNewWorksheet("with_slice")
+	dave := NewText("Dave")
+
+	ws.MustInsert("names", 0, bob)
+	ws.MustInsert("names", 0, alice)
+	ws.MustInsert("names", 2, carol)
+	require.Equal(s.T(), []Value{alice, bob, carol}, ws.MustGetSlice("names"))
+
+	ws.MustSetAt("names", 1, dave)
+	require.Equal(s.T(), []Value{alice, dave, carol}, ws.MustGetSlice("names"))
+
+	ws.MustMove("names", 2, 0)
+	require.Equal(s.T(), []Value{carol, alice, dave}, ws.MustGetSlice("names"))
+
+	ws.MustClear("names")
+	require.Len(s.T(), ws.MustGetSlice("names"), 0)
+}
+
+func (s *Zuite) TestSliceErrors_insertSetAtMoveClear() {
+	ws := s.defs.MustNewWorksheet("with_slice")
+	ws.MustAppend("names", alice)
+
+	require.EqualError(s.T(), ws.Insert("names", 2, bob), "index out of range")
+	require.EqualError(s.T(), ws.Insert("names", 0, NewBool(true)), "cannot insert value of type bool into []text")
+	require.EqualError(s.T(), ws.SetAt("names", 1, bob), "index out of range")
+	require.EqualError(s.T(), ws.SetAt("names", 0, NewBool(true)), "cannot assign value of type bool to text")
+	require.EqualError(s.T(), ws.Move("names", 0, 1), "index out of range")
+	require.EqualError(s.T(), ws.Clear("nope"), "unknown field nope")
+	require.Equal(s.T(), []Value{alice}, ws.MustGetSlice("names"))
+
+	simple := s.defs.MustNewWorksheet("simple")
+	require.EqualError(s.T(), simple.Insert("name", 0, alice), "Insert on non-slice field name")
+	require.EqualError(s.T(), simple.SetAt("name", 0, alice), "SetAt on non-slice field name")
+	require.EqualError(s.T(), simple.Move("name", 0, 0), "Move on non-slice field name")
+	require.EqualError(s.T(), simple.Clear("name"), "Clear on non-slice field name")
+}
+
+func (s *Zuite) TestSliceOfRefs_insertSetAtMoveClearParents() {
+	ws := s.defs.MustNewWorksheet("with_slice_of_refs")
+	simple1 := s.defs.MustNewWorksheet("simple")
+	simple2 := s.defs.MustNewWorksheet("simple")
+	parentsOf := func(child *Worksheet) map[string]*Worksheet {
+		return child.parents["with_slice_of_refs"][42]
+	}
+
+	ws.MustInsert("many_simples", 0, simple1)
+	require.Equal(s.T(), map[string]*Worksheet{ws.Id(): ws}, parentsOf(simple1))
+
+	ws.MustSetAt("many_simples", 0, simple2)
+	require.Len(s.T(), parentsOf(simple1), 0)
+	require.Equal(s.T(), map[string]*Worksheet{ws.Id(): ws}, parentsOf(simple2))
+
+	// moving keeps parent pointers, even when the element is re-ranked
+	ws.MustInsert("many_simples", 0, simple1)
+	ws.MustMove("many_simples", 0, 1)
+	require.Equal(s.T(), []Value{simple2, simple1}, ws.MustGetSlice("many_simples"))
+	require.Equal(s.T(), map[string]*Worksheet{ws.Id(): ws}, parentsOf(simple1))
+	require.Equal(s.T(), map[string]*Worksheet{ws.Id(): ws}, parentsOf(simple2))
+
+	ws.MustClear("many_simples")
+	require.Len(s.T(), parentsOf(simple1), 0)
+	require.Len(s.T(), parentsOf(simple2), 0)
+}
+
+func (s *Zuite) TestSliceEdit_dependentsComputedOnce() {
+	calls := make(map[string]int)
+	plus := func(name string, args ...string) countingPlus {
+		return countingPlus{args, calls, name}
+	}
+	defs := MustNewDefinitions(strings.NewReader(defsForDiamonds), Options{
+		Plugins: map[string]map[string]ComputedBy{
+			"top": {
+				"left":  plus("left", "a"),
+				"right": plus("right", "a", "a"),
+				"apex":  plus("apex", "left", "right"),
+				"total": plus("total", "apex", "sides.c", "sides.b"),
+			},
+			"side": {
+				"c": plus("c", "b"),
+			},
+		},
+	})
+	side1 := defs.MustNewWorksheet("side")
+	side2 := defs.MustNewWorksheet("side")
+	top := defs.MustNewWorksheet("top")
+
+	for k := range calls {
+		delete(calls, k)
+	}
+	err := NewEdit().
+		Insert("sides", 0, side1).
+		Insert("sides", 0, side2).
+		Move("sides", 0, 1).
+		SetAt("sides", 0, side2).
+		Apply(top)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), map[string]int{"total": 1}, calls)
+
+	for k := range calls {
+		delete(calls, k)
+	}
+	top.MustClear("sides")
+	require.Equal(s.T(), map[string]int{"total": 1}, calls)
+}
+
+var defsForIndexing = `
+type loan worksheet {
+	1:borrowers    []borrower
+	2:primary_name text computed_by {
+		return borrowers[0].first_name
+	}
+	3:last_name    text computed_by {
+		return last(borrowers).first_name
+	}
+	4:co_borrowers []borrower computed_by {
+		return borrowers[1:]
+	}
+	5:has_bob      bool computed_by {
+		return contains(borrowers.first_name, "Bob")
+	}
+}
+
+type borrower worksheet {
+	1:first_name text
+}`
+
+func (s *Zuite) TestSliceIndexing_computedBy() {
+	defs := MustNewDefinitions(strings.NewReader(defsForIndexing))
+	borrower := func(name string) *Worksheet {
+		ws := defs.MustNewWorksheet("borrower")
+		ws.MustSet("first_name", NewText(name))
+		return ws
+	}
+	alice, bob := borrower("Alice"), borrower("Bob")
+
+	loan := defs.MustNewWorksheet("loan")
+	require.Equal(s.T(), vUndefined, loan.MustGet("primary_name"))
+	require.Equal(s.T(), vUndefined, loan.MustGet("last_name"))
+	require.Equal(s.T(), NewBool(false), loan.MustGet("has_bob"))
+
+	loan.MustAppend("borrowers", alice)
+	loan.MustAppend("borrowers", bob)
+	require.Equal(s.T(), NewText("Alice"), loan.MustGet("primary_name"))
+	require.Equal(s.T(), NewText("Bob"), loan.MustGet("last_name"))
+	require.Equal(s.T(), []Value{bob}, loan.MustGetSlice("co_borrowers"))
+	require.Equal(s.T(), NewBool(true), loan.MustGet("has_bob"))
+
+	// edits of an indexed element propagate
+	alice.MustSet("first_name", NewText("Carol"))
+	require.Equal(s.T(), NewText("Carol"), loan.MustGet("primary_name"))
+	bob.MustSet("first_name", NewText("Dave"))
+	require.Equal(s.T(), NewText("Dave"), loan.MustGet("last_name"))
+	require.Equal(s.T(), NewBool(false), loan.MustGet("has_bob"))
+
+	// so do changes to the positions
+	loan.MustMove("borrowers", 1, 0)
+	require.Equal(s.T(), NewText("Dave"), loan.MustGet("primary_name"))
+	require.Equal(s.T(), NewText("Carol"), loan.MustGet("last_name"))
+	require.Equal(s.T(), []Value{alice}, loan.MustGetSlice("co_borrowers"))
+}
+
+func (s *Zuite) TestSliceIndexing_membersOfCalls() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type order worksheet {
+		1:main      item
+		2:fallback  item
+		3:use_main  bool
+		4:price     number[2] computed_by {
+			return first_of(main, fallback).price
+		}
+		5:chosen    number[2] computed_by {
+			return if(use_main, main, fallback).price
+		}
+	}
+
+	type item worksheet {
+		1:price number[2]
+	}`))
+	main, fallback := defs.MustNewWorksheet("item"), defs.MustNewWorksheet("item")
+	main.MustSet("price", MustNewValue("5"))
+	fallback.MustSet("price", MustNewValue("9"))
+
+	order := defs.MustNewWorksheet("order")
+	order.MustSet("main", main)
+	order.MustSet("fallback", fallback)
+	order.MustSet("use_main", NewBool(false))
+	require.Equal(s.T(), "5", order.MustGet("price").String())
+	require.Equal(s.T(), "9", order.MustGet("chosen").String())
+
+	// edits of the fields selected propagate
+	main.MustSet("price", MustNewValue("7"))
+	fallback.MustSet("price", MustNewValue("11"))
+	require.Equal(s.T(), "7", order.MustGet("price").String())
+	require.Equal(s.T(), "11", order.MustGet("chosen").String())
+	order.MustSet("use_main", NewBool(true))
+	require.Equal(s.T(), "7", order.MustGet("chosen").String())
+}
+
+var defsForSorting = `
+type application worksheet {
+	1:applicants   []applicant
+	2:by_score     []applicant computed_by {
+		return sort_by(applicants, "credit.score")
+	}
+	3:top_name     text computed_by {
+		return last(sort_by(applicants, "credit.score")).name
+	}
+	4:middle_score number[0] computed_by {
+		return median(applicants.credit.score) round down 0
+	}
+}
+
+type applicant worksheet {
+	1:name   text
+	2:credit credit
+}
+
+type credit worksheet {
+	1:score number[0]
+}`
+
+func (s *Zuite) TestSliceSorting_computedBy() {
+	defs := MustNewDefinitions(strings.NewReader(defsForSorting))
+	applicant := func(name string, score int) *Worksheet {
+		credit := defs.MustNewWorksheet("credit")
+		credit.MustSet("score", NewNumberFromInt(score))
+		ws := defs.MustNewWorksheet("applicant")
+		ws.MustSet("name", NewText(name))
+		ws.MustSet("credit", credit)
+		return ws
+	}
+	alice, bob, carol := applicant("Alice", 720), applicant("Bob", 680), applicant("Carol", 750)
+
+	app := defs.MustNewWorksheet("application")
+	for _, ws := range []*Worksheet{alice, bob, carol} {
+		app.MustAppend("applicants", ws)
+	}
+	require.Equal(s.T(), []Value{bob, alice, carol}, app.MustGetSlice("by_score"))
+	require.Equal(s.T(), NewText("Carol"), app.MustGet("top_name"))
+	require.Equal(s.T(), NewNumberFromInt(720), app.MustGet("middle_score"))
+
+	// edits of the sort key propagate
+	bob.MustGet("credit").(*Worksheet).MustSet("score", NewNumberFromInt(800))
+	require.Equal(s.T(), []Value{alice, carol, bob}, app.MustGetSlice("by_score"))
+	require.Equal(s.T(), NewText("Bob"), app.MustGet("top_name"))
+	require.Equal(s.T(), NewNumberFromInt(750), app.MustGet("middle_score"))
+
+	// undefined keys make the sorted slice undefined
+	carol.MustGet("credit").(*Worksheet).MustUnset("score")
+	require.Nil(s.T(), app.MustGetSlice("by_score"))
+	require.Equal(s.T(), vUndefined, app.MustGet("top_name"))
+	require.Equal(s.T(), vUndefined, app.MustGet("middle_score"))
+}
+
+func (s *Zuite) TestSliceSorting_unknownField() {
+	_, err := NewDefinitions(strings.NewReader(`
+	type application worksheet {
+		1:applicants []applicant
+		2:by_score   []applicant computed_by {
+			return sort_by(applicants, "score")
+		}
+	}
+
+	type applicant worksheet {
+		1:name text
+	}`))
+	require.EqualError(s.T(), err, "application.by_score references unknown arg applicants.score")
+}
+
+func (s *Zuite) TestSliceUpdate_insertOnlyStoresInsertedElement() {
+	var wsId string
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		ws := s.defs.MustNewWorksheet("with_slice")
+		ws.MustAppend("names", alice)
+		ws.MustAppend("names", bob)
+		wsId = ws.Id()
+
+		session := s.store.Open(tx)
+		_, err := session.Save(ws)
+		return err
+	})
+
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		session := s.store.Open(tx)
+		ws, err := session.Load(wsId)
+		if err != nil {
+			return err
+		}
+		ws.MustInsert("names", 0, carol)
+
+		_, err = session.Update(ws)
+		return err
+	})
+
+	snap := s.snapshotDbState()
+	require.Len(s.T(), snap.sliceElementsRecs, 3)
+
+	s.MustRunTransaction(func(tx *runner.Tx) error {
+		session := s.store.Open(tx)
+		ws, err := session.Load(wsId)
+		if err != nil {
+			return err
+		}
+		require.Equal(s.T(), []Value{carol, alice, bob}, ws.MustGetSlice("names"))
+		return nil
+	})
+}
+
 func (s *Zuite) TestSliceSave() {
 	ws := s.defs.MustNewWorksheet("with_slice")
 	ws.MustAppend("names", alice)
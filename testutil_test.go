@@ -58,6 +58,10 @@ type with_slice worksheet {
 	42:names []text
 }
 
+type with_nested_slices worksheet {
+	42:matrix [][]number[2]
+}
+
 type with_slice_of_refs worksheet {
 	42:many_simples []simple
 }
@@ -95,6 +99,7 @@ type DefaultMappingsTest worksheet {
 
 func forciblySetId(ws *Worksheet, id string) {
 	ws.data[indexId] = NewText(id)
+	ws.id = id
 }
 
 type allDefs struct {
@@ -0,0 +1,403 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	"github.com/homelight/worksheets"
+)
+
+// generator accumulates the generated source, and keeps track of identifiers
+// to detect collisions, e.g. fields `first_name` and `FirstName` which would
+// both yield a `FirstName` getter.
+type generator struct {
+	b        bytes.Buffer
+	topLevel map[string]string
+}
+
+func generate(defs *worksheets.Definitions, pkg string) ([]byte, error) {
+	g := &generator{
+		topLevel: make(map[string]string),
+	}
+
+	var hasWorksheets bool
+	for _, typ := range defs.Types() {
+		if _, ok := typ.(*worksheets.Definition); ok {
+			hasWorksheets = true
+		}
+	}
+
+	g.printf("// Code generated by wsgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg)
+	if hasWorksheets {
+		g.printf("import (\n")
+		g.printf("\t\"fmt\"\n\n")
+		g.printf("\t\"github.com/homelight/worksheets\"\n")
+		g.printf(")\n")
+	}
+
+	for _, typ := range defs.Types() {
+		var err error
+		switch t := typ.(type) {
+		case *worksheets.EnumType:
+			err = g.enum(t)
+		case *worksheets.Definition:
+			err = g.worksheet(t)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// When the generated code does not parse, the unformatted source is
+	// returned alongside the error to help debugging.
+	src, err := format.Source(g.b.Bytes())
+	if err != nil {
+		return g.b.Bytes(), fmt.Errorf("generated code does not parse: %s", err)
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format, args...)
+}
+
+func (g *generator) declare(ident, origin string) error {
+	if other, ok := g.topLevel[ident]; ok {
+		return fmt.Errorf("%s and %s both generate %s", other, origin, ident)
+	}
+	g.topLevel[ident] = origin
+	return nil
+}
+
+func (g *generator) enum(enum *worksheets.EnumType) error {
+	typeName := camelCase(enum.Name())
+	if err := g.declare(typeName, enum.Name()); err != nil {
+		return err
+	}
+
+	g.printf("\n// %s represents the values of the %s enum.\n", typeName, enum.Name())
+	g.printf("type %s string\n", typeName)
+
+	elements := enum.Elements()
+	if len(elements) == 0 {
+		return nil
+	}
+	g.printf("\nconst (\n")
+	for _, element := range elements {
+		suffix := camelCase(element)
+		if suffix == "" {
+			return fmt.Errorf("%s: cannot derive constant name for %q", enum.Name(), element)
+		}
+		constName := typeName + suffix
+		if err := g.declare(constName, fmt.Sprintf("%s %q", enum.Name(), element)); err != nil {
+			return err
+		}
+		g.printf("\t%s %s = %q\n", constName, typeName, element)
+	}
+	g.printf(")\n")
+	return nil
+}
+
+func (g *generator) worksheet(def *worksheets.Definition) error {
+	var (
+		name     = def.Name()
+		typeName = camelCase(name)
+	)
+	for _, ident := range []string{typeName, "New" + typeName, "Wrap" + typeName} {
+		if err := g.declare(ident, name); err != nil {
+			return err
+		}
+	}
+
+	g.printf("\n// %s is a typed wrapper of the %s worksheet.\n", typeName, name)
+	g.printf("type %s struct {\n", typeName)
+	g.printf("\tws *worksheets.Worksheet\n")
+	g.printf("}\n")
+
+	g.printf("\n// New%s creates a new %s worksheet.\n", typeName, name)
+	g.printf("func New%s(defs *worksheets.Definitions) (*%s, error) {\n", typeName, typeName)
+	g.printf("\tws, err := defs.NewWorksheet(%q)\n", name)
+	g.printf("\tif err != nil {\n")
+	g.printf("\t\treturn nil, err\n")
+	g.printf("\t}\n")
+	g.printf("\treturn &%s{ws}, nil\n", typeName)
+	g.printf("}\n")
+
+	g.printf("\n// Wrap%s wraps an existing %s worksheet, e.g. one loaded from a store.\n", typeName, name)
+	g.printf("func Wrap%s(ws *worksheets.Worksheet) (*%s, error) {\n", typeName, typeName)
+	g.printf("\tif ws.Name() != %q {\n", name)
+	g.printf("\t\treturn nil, fmt.Errorf(\"cannot wrap %%s worksheet as %s\", ws.Name())\n", name)
+	g.printf("\t}\n")
+	g.printf("\treturn &%s{ws}, nil\n", typeName)
+	g.printf("}\n")
+
+	g.printf("\n// Worksheet returns the underlying worksheet.\n")
+	g.printf("func (w *%s) Worksheet() *worksheets.Worksheet {\n", typeName)
+	g.printf("\treturn w.ws\n")
+	g.printf("}\n")
+
+	g.printf("\nfunc (w *%s) Id() string {\n", typeName)
+	g.printf("\treturn w.ws.Id()\n")
+	g.printf("}\n")
+
+	g.printf("\nfunc (w *%s) Version() int {\n", typeName)
+	g.printf("\treturn w.ws.Version()\n")
+	g.printf("}\n")
+
+	methods := map[string]string{
+		"Worksheet": "Worksheet",
+		"Id":        "id",
+		"Version":   "version",
+	}
+	for _, field := range sortedFields(def) {
+		if field.Name() == "id" || field.Name() == "version" {
+			continue
+		}
+		var err error
+		if sliceType, ok := field.Type().(*worksheets.SliceType); ok {
+			err = g.sliceField(typeName, methods, field, sliceType)
+		} else {
+			err = g.field(typeName, methods, field)
+		}
+		if err != nil {
+			return fmt.Errorf("%s.%s: %s", name, field.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func declareMethods(methods map[string]string, field *worksheets.Field, names ...string) error {
+	for _, name := range names {
+		if other, ok := methods[name]; ok {
+			return fmt.Errorf("method %s collides with %s", name, other)
+		}
+		methods[name] = field.Name()
+	}
+	return nil
+}
+
+func (g *generator) field(typeName string, methods map[string]string, field *worksheets.Field) error {
+	var (
+		name   = field.Name()
+		method = camelCase(name)
+		conv   = converterFor(field.Type())
+	)
+	if field.IsComputedBy() {
+		if err := declareMethods(methods, field, method); err != nil {
+			return err
+		}
+	} else {
+		if err := declareMethods(methods, field, method, "Set"+method, "Unset"+method); err != nil {
+			return err
+		}
+	}
+
+	g.printf("\n// %s returns the value of %s, and whether it is set.\n", method, name)
+	g.printf("func (w *%s) %s() (%s, bool) {\n", typeName, method, conv.goType)
+	g.printf("\tvalue := w.ws.MustGet(%q)\n", name)
+	conv.printUnwrap(g)
+	g.printf("}\n")
+
+	if field.IsComputedBy() {
+		return nil
+	}
+
+	g.printf("\n// Set%s sets %s.\n", method, name)
+	g.printf("func (w *%s) Set%s(value %s) error {\n", typeName, method, conv.goType)
+	if conv.nilable {
+		g.printf("\tif value == nil {\n")
+		g.printf("\t\treturn w.ws.Unset(%q)\n", name)
+		g.printf("\t}\n")
+	}
+	g.printf("\treturn w.ws.Set(%q, %s)\n", name, conv.wrap("value"))
+	g.printf("}\n")
+
+	g.printf("\n// Unset%s unsets %s.\n", method, name)
+	g.printf("func (w *%s) Unset%s() error {\n", typeName, method)
+	g.printf("\treturn w.ws.Unset(%q)\n", name)
+	g.printf("}\n")
+
+	return nil
+}
+
+func (g *generator) sliceField(typeName string, methods map[string]string, field *worksheets.Field, sliceType *worksheets.SliceType) error {
+	var (
+		name   = field.Name()
+		method = camelCase(name)
+		conv   = converterFor(sliceType.ElementType())
+	)
+	if field.IsComputedBy() {
+		if err := declareMethods(methods, field, method+"Len", method+"At"); err != nil {
+			return err
+		}
+	} else {
+		if err := declareMethods(methods, field, method+"Len", method+"At", "Append"+method, "Del"+method); err != nil {
+			return err
+		}
+	}
+
+	g.printf("\n// %sLen returns the number of elements of %s.\n", method, name)
+	g.printf("func (w *%s) %sLen() int {\n", typeName, method)
+	g.printf("\treturn len(w.ws.MustGetSlice(%q))\n", name)
+	g.printf("}\n")
+
+	g.printf("\n// %sAt returns the element at index i of %s, and whether it is set.\n", method, name)
+	g.printf("func (w *%s) %sAt(i int) (%s, bool) {\n", typeName, method, conv.goType)
+	g.printf("\tvalue := w.ws.MustGetSlice(%q)[i]\n", name)
+	conv.printUnwrap(g)
+	g.printf("}\n")
+
+	if field.IsComputedBy() {
+		return nil
+	}
+
+	g.printf("\n// Append%s appends value to %s.\n", method, name)
+	g.printf("func (w *%s) Append%s(value %s) error {\n", typeName, method, conv.goType)
+	if conv.nilable {
+		g.printf("\tif value == nil {\n")
+		g.printf("\t\treturn w.ws.Append(%q, worksheets.NewUndefined())\n", name)
+		g.printf("\t}\n")
+	}
+	g.printf("\treturn w.ws.Append(%q, %s)\n", name, conv.wrap("value"))
+	g.printf("}\n")
+
+	g.printf("\n// Del%s deletes the element at index i of %s.\n", method, name)
+	g.printf("func (w *%s) Del%s(i int) error {\n", typeName, method)
+	g.printf("\treturn w.ws.Del(%q, i)\n", name)
+	g.printf("}\n")
+
+	return nil
+}
+
+// converter describes how a worksheet type maps to a Go type, and how to
+// convert values back and forth.
+type converter struct {
+	// goType is the Go type used in generated signatures.
+	goType string
+
+	// zero is the zero value of goType, returned when values are undefined.
+	zero string
+
+	// nilable indicates whether goType can be nil, in which case nil maps to
+	// undefined.
+	nilable bool
+
+	// valueType is the type of Value to type assert, e.g. `*worksheets.Text`.
+	// When empty, values are passed through untyped.
+	valueType string
+
+	// unwrapFormat converts a value of valueType named `typed`, to goType.
+	unwrapFormat string
+
+	// wrapFormat converts an expression of goType to a worksheets.Value.
+	wrapFormat string
+}
+
+func converterFor(typ worksheets.Type) converter {
+	switch t := typ.(type) {
+	case *worksheets.TextType:
+		return converter{
+			goType:       "string",
+			zero:         `""`,
+			valueType:    "*worksheets.Text",
+			unwrapFormat: "typed.Value()",
+			wrapFormat:   "worksheets.NewText(%s)",
+		}
+	case *worksheets.BoolType:
+		return converter{
+			goType:       "bool",
+			zero:         "false",
+			valueType:    "*worksheets.Bool",
+			unwrapFormat: "typed.Value()",
+			wrapFormat:   "worksheets.NewBool(%s)",
+		}
+	case *worksheets.NumberType:
+		return converter{
+			goType:       "*worksheets.Number",
+			zero:         "nil",
+			nilable:      true,
+			valueType:    "*worksheets.Number",
+			unwrapFormat: "typed",
+			wrapFormat:   "%s",
+		}
+	case *worksheets.EnumType:
+		typeName := camelCase(t.Name())
+		return converter{
+			goType:       typeName,
+			zero:         `""`,
+			valueType:    "*worksheets.Text",
+			unwrapFormat: typeName + "(typed.Value())",
+			wrapFormat:   "worksheets.NewText(string(%s))",
+		}
+	case *worksheets.Definition:
+		typeName := camelCase(t.Name())
+		return converter{
+			goType:       "*" + typeName,
+			zero:         "nil",
+			nilable:      true,
+			valueType:    "*worksheets.Worksheet",
+			unwrapFormat: "&" + typeName + "{typed}",
+			wrapFormat:   "%s.ws",
+		}
+	default:
+		// Types without a natural Go counterpart, such as slices of slices,
+		// are exposed as untyped values.
+		return converter{
+			goType:     "worksheets.Value",
+			zero:       "nil",
+			nilable:    true,
+			wrapFormat: "%s",
+		}
+	}
+}
+
+func (conv converter) wrap(expr string) string {
+	return fmt.Sprintf(conv.wrapFormat, expr)
+}
+
+func (conv converter) printUnwrap(g *generator) {
+	if conv.valueType == "" {
+		g.printf("\tif _, ok := value.(*worksheets.Undefined); ok {\n")
+		g.printf("\t\treturn nil, false\n")
+		g.printf("\t}\n")
+		g.printf("\treturn value, true\n")
+		return
+	}
+	g.printf("\ttyped, ok := value.(%s)\n", conv.valueType)
+	g.printf("\tif !ok {\n")
+	g.printf("\t\treturn %s, false\n", conv.zero)
+	g.printf("\t}\n")
+	g.printf("\treturn %s, true\n", conv.unwrapFormat)
+}
+
+// camelCase converts names such as `first_name` to `FirstName`. Any character
+// which is neither a letter nor a digit is treated as a separator.
+func camelCase(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, part := range parts {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/homelight/worksheets"
+)
+
+type Zuite struct {
+	suite.Suite
+}
+
+func TestRunAllTheTests(t *testing.T) {
+	suite.Run(t, new(Zuite))
+}
+
+func (s *Zuite) TestCamelCase() {
+	cases := map[string]string{
+		"name":          "Name",
+		"first_name":    "FirstName",
+		"slice_of_Ping": "SliceOfPing",
+		"Ping":          "Ping",
+		"the_devil":     "TheDevil",
+		"Jr.":           "Jr",
+		"42!":           "42",
+		"!":             "",
+	}
+	for input, expected := range cases {
+		assert.Equal(s.T(), expected, camelCase(input), input)
+	}
+}
+
+func (s *Zuite) TestGenerate() {
+	defs := worksheets.MustNewDefinitions(strings.NewReader(`
+	type team_member enum {
+		"pratik",
+		"the_devil",
+	}
+
+	type borrower worksheet {
+		1:first_name text
+		2:who        team_member
+		3:employer   employer
+		4:incomes    []number[2]
+		5:total      number[2] computed_by { return sum(incomes) }
+	}
+
+	type employer worksheet {
+		1:name text
+	}`))
+
+	src, err := generate(defs, "example")
+	require.NoError(s.T(), err)
+
+	expectedSnippets := []string{
+		"// Code generated by wsgen. DO NOT EDIT.\n\npackage example\n",
+
+		// enums
+		"type TeamMember string\n",
+		"TeamMemberPratik   TeamMember = \"pratik\"\n",
+		"TeamMemberTheDevil TeamMember = \"the_devil\"\n",
+
+		// wrappers
+		"type Borrower struct {\n\tws *worksheets.Worksheet\n}\n",
+		"func NewBorrower(defs *worksheets.Definitions) (*Borrower, error) {\n",
+		"func WrapEmployer(ws *worksheets.Worksheet) (*Employer, error) {\n",
+
+		// text
+		`func (w *Borrower) FirstName() (string, bool) {
+	value := w.ws.MustGet("first_name")
+	typed, ok := value.(*worksheets.Text)
+	if !ok {
+		return "", false
+	}
+	return typed.Value(), true
+}`,
+		`func (w *Borrower) SetFirstName(value string) error {
+	return w.ws.Set("first_name", worksheets.NewText(value))
+}`,
+
+		// enum
+		"func (w *Borrower) Who() (TeamMember, bool) {\n",
+		`w.ws.Set("who", worksheets.NewText(string(value)))`,
+
+		// refs
+		"func (w *Borrower) Employer() (*Employer, bool) {\n",
+		"return &Employer{typed}, true\n",
+		`w.ws.Set("employer", value.ws)`,
+
+		// slices
+		"func (w *Borrower) IncomesLen() int {\n",
+		"func (w *Borrower) IncomesAt(i int) (*worksheets.Number, bool) {\n",
+		"func (w *Borrower) AppendIncomes(value *worksheets.Number) error {\n",
+		"func (w *Borrower) DelIncomes(i int) error {\n",
+
+		// computed
+		"func (w *Borrower) Total() (*worksheets.Number, bool) {\n",
+	}
+	for _, snippet := range expectedSnippets {
+		assert.Contains(s.T(), string(src), snippet)
+	}
+
+	// computed fields are read-only
+	assert.NotContains(s.T(), string(src), "SetTotal")
+	assert.NotContains(s.T(), string(src), "UnsetTotal")
+}
+
+func (s *Zuite) TestGenerate_typeChecks() {
+	defs := worksheets.MustNewDefinitions(strings.NewReader(`
+	type team_member enum {
+		"pratik",
+		"the_devil",
+	}
+
+	type borrower worksheet {
+		1:first_name  text
+		2:who         team_member
+		3:employer    employer
+		4:incomes     []number[2]
+		5:total       number[2] computed_by { return sum(incomes) }
+		6:is_employed bool
+		7:employers   []employer
+		8:whos        []team_member
+		9:names       []text
+	}
+
+	type employer worksheet {
+		1:name text
+	}`))
+
+	src, err := generate(defs, "example")
+	require.NoError(s.T(), err)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "borrower_ws.go", src, 0)
+	require.NoError(s.T(), err)
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+	}
+	_, err = conf.Check("example", fset, []*ast.File{file}, nil)
+	require.NoError(s.T(), err, string(src))
+}
+
+func (s *Zuite) TestGenerate_onlyEnums() {
+	defs := worksheets.MustNewDefinitions(strings.NewReader(`
+	type yes_or_no enum {
+		"yes",
+		"no",
+	}`))
+
+	src, err := generate(defs, "example")
+	require.NoError(s.T(), err)
+	assert.NotContains(s.T(), string(src), "import")
+}
+
+func (s *Zuite) TestGenerate_collisions() {
+	cases := map[string]string{
+		`type borrower worksheet {
+			1:first_name text
+			2:FirstName  text
+		}`: `borrower.first_name: method FirstName collides with FirstName`,
+
+		`type borrower worksheet {
+			1:worksheet text
+		}`: `borrower.worksheet: method Worksheet collides with Worksheet`,
+
+		`type borrower worksheet {
+			1:name     text
+			2:set_name text
+		}`: `borrower.set_name: method SetName collides with name`,
+
+		`type borrower worksheet {}
+		type Borrower worksheet {}`: `Borrower and borrower both generate Borrower`,
+
+		`type some_enum enum {
+			"Jr.",
+			"Jr",
+		}`: `some_enum "Jr" and some_enum "Jr." both generate SomeEnumJr`,
+
+		`type some_enum enum {
+			"!",
+		}`: `some_enum: cannot derive constant name for "!"`,
+	}
+	for input, msg := range cases {
+		defs := worksheets.MustNewDefinitions(strings.NewReader(input))
+		_, err := generate(defs, "example")
+		assert.EqualError(s.T(), err, msg, input)
+	}
+}
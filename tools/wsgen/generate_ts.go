@@ -0,0 +1,236 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/homelight/worksheets"
+)
+
+// generateTypeScript generates TypeScript declarations matching the JSON
+// produced by `Worksheet.MarshalJSON`, i.e. a graph of worksheets keyed by
+// their ids, where
+//
+// - numbers are represented as strings to preserve their precision,
+// - refs to other worksheets are represented by the id of the worksheet,
+// - undefined fields are absent, and undefined slice elements are null.
+//
+// Optionally, a decoder is generated to resolve ids into nested objects.
+func generateTypeScript(defs *worksheets.Definitions, withDecoder bool) ([]byte, error) {
+	g := &generator{
+		topLevel: make(map[string]string),
+	}
+
+	g.printf("// Code generated by wsgen. DO NOT EDIT.\n")
+
+	var wsTypeNames []string
+	for _, typ := range defs.Types() {
+		var err error
+		switch t := typ.(type) {
+		case *worksheets.EnumType:
+			err = g.tsEnum(t)
+		case *worksheets.Definition:
+			wsTypeNames = append(wsTypeNames, camelCase(t.Name()))
+			err = g.tsWorksheet(t)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.declare("WorksheetGraph", "graph"); err != nil {
+		return nil, err
+	}
+	g.printf("\n// WorksheetGraph is the JSON representation of worksheets, keyed by id.\n")
+	if len(wsTypeNames) == 0 {
+		g.printf("export type WorksheetGraph = { [id: string]: never };\n")
+	} else {
+		g.printf("export type WorksheetGraph = { [id: string]: %s };\n", strings.Join(wsTypeNames, " | "))
+	}
+
+	if withDecoder {
+		for _, typ := range defs.Types() {
+			if def, ok := typ.(*worksheets.Definition); ok {
+				if err := g.tsDecoder(def); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return g.b.Bytes(), nil
+}
+
+func (g *generator) tsEnum(enum *worksheets.EnumType) error {
+	typeName := camelCase(enum.Name())
+	if err := g.declare(typeName, enum.Name()); err != nil {
+		return err
+	}
+
+	var quoted []string
+	for _, element := range enum.Elements() {
+		quoted = append(quoted, strconv.Quote(element))
+	}
+	union := "never"
+	if len(quoted) != 0 {
+		union = strings.Join(quoted, " | ")
+	}
+
+	g.printf("\n// %s represents the values of the %s enum.\n", typeName, enum.Name())
+	g.printf("export type %s = %s;\n", typeName, union)
+	return nil
+}
+
+func (g *generator) tsWorksheet(def *worksheets.Definition) error {
+	typeName := camelCase(def.Name())
+	if err := g.declare(typeName, def.Name()); err != nil {
+		return err
+	}
+
+	g.printf("\n// %s is the JSON representation of the %s worksheet.\n", typeName, def.Name())
+	g.printf("export interface %s {\n", typeName)
+	for _, field := range sortedFields(def) {
+		optional := "?"
+		if field.Name() == "id" || field.Name() == "version" {
+			optional = ""
+		}
+		g.printf("\t%s%s: %s; // %s\n", field.Name(), optional, tsType(field.Type(), false), field.Type())
+	}
+	g.printf("}\n")
+	return nil
+}
+
+func (g *generator) tsDecoder(def *worksheets.Definition) error {
+	var (
+		typeName = camelCase(def.Name())
+		nodeName = typeName + "Node"
+		funcName = "decode" + typeName
+	)
+	for _, ident := range []string{nodeName, funcName} {
+		if err := g.declare(ident, def.Name()); err != nil {
+			return err
+		}
+	}
+
+	g.printf("\n// %s is the %s worksheet, with refs resolved.\n", nodeName, def.Name())
+	g.printf("export interface %s {\n", nodeName)
+	for _, field := range sortedFields(def) {
+		optional := "?"
+		if field.Name() == "id" || field.Name() == "version" {
+			optional = ""
+		}
+		g.printf("\t%s%s: %s;\n", field.Name(), optional, tsType(field.Type(), true))
+	}
+	g.printf("}\n")
+
+	g.printf("\n// %s resolves the %s worksheet with the given id from the graph.\n", funcName, def.Name())
+	g.printf("export function %s(graph: WorksheetGraph, id: string, seen: { [id: string]: any } = {}): %s {\n", funcName, nodeName)
+	g.printf("\tif (seen[id] !== undefined) {\n")
+	g.printf("\t\treturn seen[id];\n")
+	g.printf("\t}\n")
+	g.printf("\tconst raw = graph[id] as %s;\n", typeName)
+	g.printf("\tif (raw === undefined) {\n")
+	g.printf("\t\tthrow new Error(`unknown worksheet ${id}`);\n")
+	g.printf("\t}\n")
+	g.printf("\tconst node = {} as %s;\n", nodeName)
+	g.printf("\tseen[id] = node;\n")
+	for _, field := range sortedFields(def) {
+		access := "raw." + field.Name()
+		if field.Name() == "id" || field.Name() == "version" {
+			g.printf("\tnode.%s = %s;\n", field.Name(), access)
+			continue
+		}
+		g.printf("\tif (%s !== undefined) {\n", access)
+		g.printf("\t\tnode.%s = %s;\n", field.Name(), tsDecodeExpr(field.Type(), access, 0))
+		g.printf("\t}\n")
+	}
+	g.printf("\treturn node;\n")
+	g.printf("}\n")
+	return nil
+}
+
+// tsType returns the TypeScript type for a worksheet type. When resolved is
+// set, refs are represented by their node type rather than their id.
+func tsType(typ worksheets.Type, resolved bool) string {
+	switch t := typ.(type) {
+	case *worksheets.TextType, *worksheets.NumberType:
+		return "string"
+	case *worksheets.BoolType:
+		return "boolean"
+	case *worksheets.UndefinedType:
+		return "null"
+	case *worksheets.EnumType:
+		return camelCase(t.Name())
+	case *worksheets.Definition:
+		if resolved {
+			return camelCase(t.Name()) + "Node"
+		}
+		return "string"
+	case *worksheets.SliceType:
+		return fmt.Sprintf("(%s | null)[]", tsType(t.ElementType(), resolved))
+	default:
+		panic(fmt.Sprintf("unexpected type %s", typ))
+	}
+}
+
+// tsDecodeExpr returns an expression decoding expr, of the JSON
+// representation of typ, into its resolved representation.
+func tsDecodeExpr(typ worksheets.Type, expr string, depth int) string {
+	switch t := typ.(type) {
+	case *worksheets.Definition:
+		return fmt.Sprintf("decode%s(graph, %s, seen)", camelCase(t.Name()), expr)
+	case *worksheets.SliceType:
+		if !hasRefs(t) {
+			return expr
+		}
+		elem := fmt.Sprintf("e%d", depth)
+		return fmt.Sprintf("%s.map((%s) => (%s === null ? null : %s))", expr, elem, elem, tsDecodeExpr(t.ElementType(), elem, depth+1))
+	default:
+		return expr
+	}
+}
+
+func hasRefs(typ worksheets.Type) bool {
+	switch t := typ.(type) {
+	case *worksheets.Definition:
+		return true
+	case *worksheets.SliceType:
+		return hasRefs(t.ElementType())
+	default:
+		return false
+	}
+}
+
+// sortedFields returns the fields of def, with the id and version first,
+// followed by all other fields sorted by name.
+func sortedFields(def *worksheets.Definition) []*worksheets.Field {
+	fields := def.Fields()
+	rank := func(field *worksheets.Field) string {
+		switch field.Name() {
+		case "id":
+			return "0"
+		case "version":
+			return "1"
+		default:
+			return "2" + field.Name()
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return rank(fields[i]) < rank(fields[j])
+	})
+	return fields
+}
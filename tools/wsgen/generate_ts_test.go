@@ -0,0 +1,113 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/homelight/worksheets"
+)
+
+var tsDefs = `
+type team_member enum {
+	"pratik",
+	"the_devil",
+}
+
+type borrower worksheet {
+	1:first_name text
+	2:who        team_member
+	3:employer   employer
+	4:incomes    []number[2]
+	5:jobs       [][]employer
+	6:happy      bool
+}
+
+type employer worksheet {
+	1:name text
+}`
+
+func (s *Zuite) TestGenerateTypeScript() {
+	defs := worksheets.MustNewDefinitions(strings.NewReader(tsDefs))
+
+	src, err := generateTypeScript(defs, false)
+	require.NoError(s.T(), err)
+
+	expected := `// Code generated by wsgen. DO NOT EDIT.
+
+// Borrower is the JSON representation of the borrower worksheet.
+export interface Borrower {
+	id: string; // text
+	version: string; // number[0]
+	employer?: string; // employer
+	first_name?: string; // text
+	happy?: boolean; // bool
+	incomes?: (string | null)[]; // []number[2]
+	jobs?: ((string | null)[] | null)[]; // [][]employer
+	who?: TeamMember; // team_member
+}
+
+// Employer is the JSON representation of the employer worksheet.
+export interface Employer {
+	id: string; // text
+	version: string; // number[0]
+	name?: string; // text
+}
+
+// TeamMember represents the values of the team_member enum.
+export type TeamMember = "pratik" | "the_devil";
+
+// WorksheetGraph is the JSON representation of worksheets, keyed by id.
+export type WorksheetGraph = { [id: string]: Borrower | Employer };
+`
+	require.Equal(s.T(), expected, string(src))
+}
+
+func (s *Zuite) TestGenerateTypeScript_withDecoder() {
+	defs := worksheets.MustNewDefinitions(strings.NewReader(tsDefs))
+
+	src, err := generateTypeScript(defs, true)
+	require.NoError(s.T(), err)
+
+	expectedSnippets := []string{
+		"export interface BorrowerNode {\n",
+		"\temployer?: EmployerNode;\n",
+		"\tjobs?: ((EmployerNode | null)[] | null)[];\n",
+		"\tincomes?: (string | null)[];\n",
+		"export function decodeBorrower(graph: WorksheetGraph, id: string, seen: { [id: string]: any } = {}): BorrowerNode {\n",
+		"\t\tnode.employer = decodeEmployer(graph, raw.employer, seen);\n",
+		"\t\tnode.jobs = raw.jobs.map((e0) => (e0 === null ? null : e0.map((e1) => (e1 === null ? null : decodeEmployer(graph, e1, seen)))));\n",
+		"\t\tnode.incomes = raw.incomes;\n",
+		"export function decodeEmployer(graph: WorksheetGraph, id: string, seen: { [id: string]: any } = {}): EmployerNode {\n",
+	}
+	for _, snippet := range expectedSnippets {
+		assert.Contains(s.T(), string(src), snippet)
+	}
+}
+
+func (s *Zuite) TestGenerateTypeScript_collisions() {
+	cases := map[string]string{
+		`type borrower worksheet {}
+		type borrower_node worksheet {}`: `borrower_node and borrower both generate BorrowerNode`,
+
+		`type worksheet_graph worksheet {}`: `worksheet_graph and graph both generate WorksheetGraph`,
+	}
+	for input, msg := range cases {
+		defs := worksheets.MustNewDefinitions(strings.NewReader(input))
+		_, err := generateTypeScript(defs, true)
+		assert.EqualError(s.T(), err, msg, input)
+	}
+}
@@ -0,0 +1,91 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command wsgen generates typed Go wrappers for worksheet definitions, or
+// TypeScript declarations of their JSON representation.
+//
+// It is meant to be invoked via `go generate`, for instance
+//
+//	//go:generate go run github.com/homelight/worksheets/tools/wsgen -in borrower.ws -out borrower_ws.go
+//
+// When not provided, the package name defaults to the package in which
+// `go generate` runs.
+//
+// With `-lang ts`, TypeScript interfaces and enum unions are generated instead,
+// and `-decoder` additionally generates functions resolving the id-keyed graph
+// into nested objects.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/homelight/worksheets"
+)
+
+func main() {
+	var (
+		in      = flag.String("in", "", "worksheet definitions file")
+		out     = flag.String("out", "", "generated file (defaults to stdout)")
+		pkg     = flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated Go file")
+		lang    = flag.String("lang", "go", "language to generate, go or ts")
+		decoder = flag.Bool("decoder", false, "generate a decoder of the JSON graph (ts only)")
+	)
+	flag.Parse()
+
+	if *in == "" || (*lang == "go" && *pkg == "") || (*lang != "go" && *lang != "ts") {
+		fmt.Fprintln(os.Stderr, "Usage: wsgen -in filename [-out filename] [-pkg name] [-lang go|ts] [-decoder]")
+		os.Exit(1)
+	}
+
+	if err := run(*in, *out, *pkg, *lang, *decoder); err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL\t%s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func run(in, out, pkg, lang string, decoder bool) error {
+	file, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	defs, err := worksheets.NewDefinitions(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", in, err)
+	}
+
+	var src []byte
+	switch lang {
+	case "go":
+		src, err = generate(defs, pkg)
+	case "ts":
+		src, err = generateTypeScript(defs, decoder)
+	}
+	if err != nil {
+		if src != nil {
+			// unformatted source, dumped to help debugging
+			os.Stderr.Write(src)
+		}
+		return fmt.Errorf("%s: %s", in, err)
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
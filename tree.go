@@ -24,6 +24,9 @@ type Definition struct {
 	name          string
 	fieldsByName  map[string]*Field
 	fieldsByIndex map[int]*Field
+	editors       []Editor
+	concurrent    bool
+	constraints   []*constraint
 }
 
 func (def *Definition) addField(field *Field) error {
@@ -49,6 +52,24 @@ func (def *Definition) addField(field *Field) error {
 	return nil
 }
 
+func (def *Definition) addConstraint(constraint *constraint) error {
+	for _, existing := range def.constraints {
+		if existing.name == constraint.name {
+			return fmt.Errorf("%s: constraint %s cannot be reused", def.name, constraint.name)
+		}
+	}
+	def.constraints = append(def.constraints, constraint)
+	return nil
+}
+
+// constraint is a named invariant of worksheets, declared in a `constraints`
+// block, which may span multiple fields, e.g. `down_payment <= price`.
+type constraint struct {
+	name    string
+	expr    expression
+	message string
+}
+
 type Field struct {
 	index         int
 	name          string
@@ -57,6 +78,28 @@ type Field struct {
 	dependents    []*Field
 	computedBy    expression
 	constrainedBy expression
+
+	// constraintMessage is the message reported when the constraint of the
+	// field is violated, see `interpolate`.
+	constraintMessage string
+
+	// required fields must be set, or be non-empty slices, for worksheets
+	// to be valid, see `Validate`.
+	required bool
+
+	// rank orders fields topologically, see rankFields.
+	rank int
+
+	// dependentsThroughRefs are the dependents of the same worksheet type
+	// which reach this field through a ref, and must therefore be recomputed
+	// on parents rather than on the edited worksheet. This is the case of
+	// recursive definitions, e.g. a `node.total` computed as the sum of
+	// `children.total`.
+	dependentsThroughRefs map[*Field]bool
+
+	// dependentsLocal are the dependents of the same worksheet type which
+	// reach this field directly.
+	dependentsLocal map[*Field]bool
 }
 
 func (f *Field) Type() Type {
@@ -75,6 +118,31 @@ func (f *Field) IsComputedBy() bool {
 	return f.computedBy != nil
 }
 
+func (f *Field) IsRequired() bool {
+	return f.required
+}
+
+// Definition returns the worksheet definition this field belongs to.
+func (f *Field) Definition() *Definition {
+	return f.def
+}
+
+// Dependents returns the computed fields which depend on this field, either
+// directly or through a selector.
+func (f *Field) Dependents() []*Field {
+	var (
+		seen       = make(map[*Field]bool)
+		dependents []*Field
+	)
+	for _, dependent := range f.dependents {
+		if !seen[dependent] {
+			seen[dependent] = true
+			dependents = append(dependents, dependent)
+		}
+	}
+	return dependents
+}
+
 type tOp string
 
 const (
@@ -93,6 +161,24 @@ const (
 	opAnd                    = "and"
 )
 
+// opSymbols maps operators to their source representation, as used when
+// printing or explaining expressions.
+var opSymbols = map[tOp]string{
+	opPlus:               "+",
+	opMinus:              "-",
+	opMult:               "*",
+	opDiv:                "/",
+	opNot:                "!",
+	opEqual:              "==",
+	opNotEqual:           "!=",
+	opGreaterThan:        ">",
+	opGreaterThanOrEqual: ">=",
+	opLessThan:           "<",
+	opLessThanOrEqual:    "<=",
+	opOr:                 "||",
+	opAnd:                "&&",
+}
+
 type tRound struct {
 	mode  RoundingMode
 	scale int
@@ -109,6 +195,10 @@ type tUnop struct {
 	expr expression
 }
 
+func (t *tUnop) String() string {
+	return printExpr(t)
+}
+
 type tBinop struct {
 	op          tOp
 	left, right expression
@@ -116,7 +206,7 @@ type tBinop struct {
 }
 
 func (t *tBinop) String() string {
-	return fmt.Sprintf("binop(%s, %s, %s, %s)", t.op, t.left, t.right, t.round)
+	return printExpr(t)
 }
 
 // tSelector represents a selector such as referencing a field `foo`, or
@@ -127,13 +217,51 @@ func (t tSelector) String() string {
 	return strings.Join(t, ".")
 }
 
+// tIndex represents indexing such as `incomes[0]`.
+type tIndex struct {
+	expr, index expression
+}
+
+func (t *tIndex) String() string {
+	return printExpr(t)
+}
+
+// tRange represents re-slicing such as `incomes[1:3]`, where either bound may
+// be omitted, e.g. `incomes[1:]`.
+type tRange struct {
+	expr, from, to expression
+}
+
+func (t *tRange) String() string {
+	return printExpr(t)
+}
+
+// tMember represents selecting from the value of an expression, such as
+// `borrowers[0].first_name`.
+type tMember struct {
+	expr expression
+	path tSelector
+}
+
+func (t *tMember) String() string {
+	return printExpr(t)
+}
+
 type tReturn struct {
 	expr expression
 }
 
+func (t *tReturn) String() string {
+	return printExpr(t)
+}
+
 // tCall represents a function invocation such as `len(some_slice)`.
 type tCall struct {
 	name  tSelector
 	args  []expression
 	round *tRound
 }
+
+func (t *tCall) String() string {
+	return printExpr(t)
+}
@@ -14,6 +14,7 @@ package worksheets
 
 import (
 	"fmt"
+	"sort"
 )
 
 // Type represents the type of a value.
@@ -129,3 +130,13 @@ func (typ *EnumType) Name() string {
 func (typ *EnumType) String() string {
 	return typ.name
 }
+
+// Elements returns the values of the enum, sorted.
+func (typ *EnumType) Elements() []string {
+	elements := make([]string, 0, len(typ.elements))
+	for element := range typ.elements {
+		elements = append(elements, element)
+	}
+	sort.Strings(elements)
+	return elements
+}
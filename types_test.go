@@ -67,3 +67,22 @@ func (s *Zuite) TestWorksheetDefinition_Fields() {
 		require.Contains(s.T(), fields, field)
 	}
 }
+
+func (s *Zuite) TestDefinitions_Types() {
+	defs := MustNewDefinitions(strings.NewReader(`
+	type yes_or_no enum {
+		"yes",
+		"no",
+	}
+	type simple worksheet {}
+	type another_simple worksheet {}`))
+
+	var names []string
+	for _, typ := range defs.Types() {
+		names = append(names, typ.Name())
+	}
+	require.Equal(s.T(), []string{"another_simple", "simple", "yes_or_no"}, names)
+
+	enum := defs.defs["yes_or_no"].(*EnumType)
+	require.Equal(s.T(), []string{"no", "yes"}, enum.Elements())
+}
@@ -469,6 +469,20 @@ type Slice struct {
 	elements []sliceElement
 }
 
+// NewSlice creates a slice of type `typ` with `elements`, to be used as an
+// element of slices of slices, e.g. appended to a `[][]number[2]` field.
+func NewSlice(typ *SliceType, elements ...Value) (*Slice, error) {
+	slice := newSlice(typ)
+	for _, element := range elements {
+		var err error
+		slice, err = slice.doAppend(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return slice, nil
+}
+
 func newSlice(typ *SliceType, values ...Value) *Slice {
 	var (
 		id       = uuid.Must(uuid.NewV4()).String()
@@ -493,6 +507,26 @@ func newSliceWithIdAndLastRank(typ *SliceType, id string, lastRank int, values .
 	return slice
 }
 
+// owned returns `value`, with slices nested in it copied with new ids. Slices
+// are immutable, and their elements stored by id, such that nested slices
+// must never be shared between fields, or worksheets.
+func owned(value Value) Value {
+	slice, ok := value.(*Slice)
+	if !ok {
+		return value
+	}
+	elements := make([]sliceElement, len(slice.elements))
+	for i, element := range slice.elements {
+		elements[i] = sliceElement{rank: element.rank, value: owned(element.value)}
+	}
+	return &Slice{
+		id:       uuid.Must(uuid.NewV4()).String(),
+		typ:      slice.typ,
+		lastRank: slice.lastRank,
+		elements: elements,
+	}
+}
+
 func (slice *Slice) Len() int {
 	return len(slice.elements)
 }
@@ -545,6 +579,128 @@ func (value *Slice) doDel(index int) (*Slice, error) {
 	}, nil
 }
 
+// doInsert inserts `value` before the element at `index`, with a rank between
+// the ranks of its neighbours. When their ranks are contiguous, the fewest
+// elements possible are re-ranked to make room: either preceding elements are
+// shifted down, or following elements up, up to the first gap in ranks. Ranks
+// are never negative, such that repeated inserts at the front eventually
+// shift following elements up.
+func (slice *Slice) doInsert(index int, value Value) (*Slice, error) {
+	if index < 0 || len(slice.elements) < index {
+		return nil, fmt.Errorf("index out of range")
+	}
+
+	// assignability check
+	if err := canAssignTo("insert", value, slice.typ.elementType); err != nil {
+		return nil, err
+	}
+
+	if index == len(slice.elements) {
+		return slice.doAppend(value)
+	}
+
+	elements := make([]sliceElement, len(slice.elements), len(slice.elements)+1)
+	copy(elements, slice.elements)
+
+	var rank int
+	if index == 0 && 0 < elements[0].rank {
+		rank = elements[0].rank - 1
+	} else if prev, next := index-1, index; 0 < index && 1 < elements[next].rank-elements[prev].rank {
+		rank = elements[prev].rank + (elements[next].rank-elements[prev].rank)/2
+	} else {
+		// Ranks are never negative, hence preceding elements can only be
+		// shifted down when the first of them has a positive rank.
+		var up, down int
+		for j := index; j < len(elements); j++ {
+			up++
+			if j+1 == len(elements) || elements[j].rank+1 < elements[j+1].rank {
+				break
+			}
+		}
+		for j := index - 1; 0 <= j; j-- {
+			down++
+			if j == 0 || elements[j-1].rank < elements[j].rank-1 {
+				break
+			}
+		}
+		if down == 0 || elements[index-down].rank == 0 || up <= down {
+			rank = elements[index].rank
+			for j := index; j < index+up; j++ {
+				elements[j].rank++
+			}
+		} else {
+			rank = elements[index-1].rank
+			for j := index - down; j < index; j++ {
+				elements[j].rank--
+			}
+		}
+	}
+
+	elements = append(elements, sliceElement{})
+	copy(elements[index+1:], elements[index:])
+	elements[index] = sliceElement{rank: rank, value: value}
+
+	lastRank := slice.lastRank
+	if last := elements[len(elements)-1].rank; lastRank < last {
+		lastRank = last
+	}
+	return &Slice{
+		id:       slice.id,
+		typ:      slice.typ,
+		lastRank: lastRank,
+		elements: elements,
+	}, nil
+}
+
+// doSetAt replaces the element at `index` with `value`, at the same rank.
+func (slice *Slice) doSetAt(index int, value Value) (*Slice, error) {
+	if index < 0 || len(slice.elements) <= index {
+		return nil, fmt.Errorf("index out of range")
+	}
+
+	// assignability check
+	if err := canAssignTo("assign", value, slice.typ.elementType); err != nil {
+		return nil, err
+	}
+
+	elements := make([]sliceElement, len(slice.elements))
+	copy(elements, slice.elements)
+	elements[index].value = value
+	return &Slice{
+		id:       slice.id,
+		typ:      slice.typ,
+		lastRank: slice.lastRank,
+		elements: elements,
+	}, nil
+}
+
+// doMove moves the element at `from` to `to`, where `to` is the index of the
+// element once moved. Only the element moved is re-ranked, unless ranks
+// around its new position are contiguous, see doInsert.
+func (slice *Slice) doMove(from, to int) (*Slice, error) {
+	if from < 0 || len(slice.elements) <= from || to < 0 || len(slice.elements) <= to {
+		return nil, fmt.Errorf("index out of range")
+	}
+	if from == to {
+		return slice, nil
+	}
+	moved := slice.elements[from].value
+	without, err := slice.doDel(from)
+	if err != nil {
+		return nil, err
+	}
+	return without.doInsert(to, moved)
+}
+
+// doClear removes all elements.
+func (slice *Slice) doClear() *Slice {
+	return &Slice{
+		id:       slice.id,
+		typ:      slice.typ,
+		lastRank: slice.lastRank,
+	}
+}
+
 func (value *Slice) Type() Type {
 	return value.typ
 }
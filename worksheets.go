@@ -15,6 +15,8 @@ package worksheets
 import (
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	uuid "github.com/satori/go.uuid"
 )
@@ -25,6 +27,19 @@ type Definitions struct {
 	defs map[string]NamedType
 }
 
+// Types returns all named types, i.e. worksheet definitions and enums, sorted
+// by name.
+func (defs *Definitions) Types() []NamedType {
+	types := make([]NamedType, 0, len(defs.defs))
+	for _, typ := range defs.defs {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name() < types[j].Name()
+	})
+	return types
+}
+
 // parentsRefs records and organizes references to all parents of a worksheet,
 // i.e. all worksheets which point directly (ref), or indirectly (e.g. via a
 // ref in a slice) to the worksheet.
@@ -71,6 +86,11 @@ type Worksheet struct {
 	// def holds the definition of this worksheet.
 	def *Definition
 
+	// id holds the identifier of this worksheet, which is also stored in its
+	// data, and never changes. Keeping it aside allows it to be read without
+	// synchronization.
+	id string
+
 	// orig holds the worksheet data as it was when it was initially loaded.
 	orig map[int]Value
 
@@ -80,6 +100,20 @@ type Worksheet struct {
 	// parents holds all the reverse pointers of worksheets pointing to this
 	// worksheet.
 	parents parentsRefs
+
+	// listeners holds the listeners notified of changes to this worksheet.
+	listeners []listener
+
+	// history holds the undo, and redo history of this worksheet, if
+	// enabled.
+	history *history
+
+	// frozen indicates that the worksheet is read-only, see Freeze.
+	frozen bool
+
+	// graph holds the lock of the graph of worksheets this worksheet is part
+	// of, when worksheets are concurrency safe.
+	graph *graphLock
 }
 
 const (
@@ -99,6 +133,14 @@ type Options struct {
 	// Plugins is a map of workshet names, to field names, to plugins for
 	// externally computed fields.
 	Plugins map[string]map[string]ComputedBy
+
+	// Editors is a map of worksheet names, to editors intercepting edits of
+	// these worksheets. Editors are invoked in order.
+	Editors map[string][]Editor
+
+	// Concurrent makes worksheets safe to read, and edit, from multiple
+	// goroutines, see `graphLock`.
+	Concurrent bool
 }
 
 func MustNewDefinitions(reader io.Reader, opts ...Options) *Definitions {
@@ -177,8 +219,24 @@ func NewDefinitions(reader io.Reader, opts ...Options) (*Definitions, error) {
 					// fields don't need to be recalculated when args are
 					// set, only upon setting a new value.
 					if field.computedBy != nil {
-						for _, ascendant := range path {
+						for i, ascendant := range path {
 							ascendant.dependents = append(ascendant.dependents, field)
+							if ascendant.def != def {
+								continue
+							}
+							// path goes from the selected field, back to the
+							// field of this worksheet
+							if i == len(path)-1 {
+								if ascendant.dependentsLocal == nil {
+									ascendant.dependentsLocal = make(map[*Field]bool)
+								}
+								ascendant.dependentsLocal[field] = true
+							} else {
+								if ascendant.dependentsThroughRefs == nil {
+									ascendant.dependentsThroughRefs = make(map[*Field]bool)
+								}
+								ascendant.dependentsThroughRefs[field] = true
+							}
 						}
 					}
 				}
@@ -186,11 +244,150 @@ func NewDefinitions(reader io.Reader, opts ...Options) (*Definitions, error) {
 		}
 	}
 
+	// Resolve dependencies of worksheet constraints, and fields referenced
+	// by constraint messages
+	for _, typ := range defs {
+		def, ok := typ.(*Definition)
+		if !ok {
+			continue
+		}
+		for _, field := range def.fieldsByIndex {
+			if name, ok := def.unknownPlaceholder(field.constraintMessage); !ok {
+				return nil, fmt.Errorf("%s.%s message references unknown field %s", def.name, field.name, name)
+			}
+		}
+		for _, constraint := range def.constraints {
+			if name, ok := def.unknownPlaceholder(constraint.message); !ok {
+				return nil, fmt.Errorf("%s constraint %s message references unknown field %s", def.name, constraint.name, name)
+			}
+			selectors := constraint.expr.selectors()
+			if len(selectors) == 0 {
+				return nil, fmt.Errorf("%s constraint %s has no dependencies", def.name, constraint.name)
+			}
+			for _, selector := range selectors {
+				if _, ok := selector.Select(def); !ok {
+					return nil, fmt.Errorf("%s constraint %s references unknown arg %s", def.name, constraint.name, selector)
+				}
+			}
+		}
+	}
+
+	if err := rankFields(defs); err != nil {
+		return nil, err
+	}
+
 	return &Definitions{
 		defs,
 	}, nil
 }
 
+// rankFields ranks computed fields in topological order of their dependencies,
+// i.e. such that fields are ranked lower than their dependents, across
+// worksheets. Recomputing computed fields by rank guarantees that they are
+// computed from up to date values, and are computed at most once per edit.
+//
+// Dependencies must be acyclic, and cycles are reported by naming all fields
+// involved, e.g. `loan.ltv -> property.value -> loan.ltv` where each field
+// depends on the next. The one exception are recursive definitions, where a
+// field depends on itself through refs only, e.g. `node.total` computed from
+// `children.total`, which are acyclic as long as worksheets do not form a
+// cycle.
+func rankFields(defs map[string]NamedType) error {
+	var names []string
+	for name, typ := range defs {
+		if _, ok := typ.(*Definition); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Depth first search, with fields ordered by decreasing finishing time
+	// yielding a topological ordering. Fields on the path being explored are
+	// tracked to detect back edges, i.e. cycles.
+	var (
+		visited = make(map[*Field]bool)
+		onPath  = make(map[*Field]bool)
+		path    []*Field
+		order   []*Field
+		visit   func(field *Field) error
+	)
+	visit = func(field *Field) error {
+		if onPath[field] {
+			return cycleError(path, field)
+		}
+		if visited[field] {
+			return nil
+		}
+		visited[field] = true
+		onPath[field] = true
+		path = append(path, field)
+		for _, dependent := range sortedDependents(field) {
+			// A field depending on itself through refs only, such as
+			// `node.total` computed from `children.total`, is computed
+			// from other worksheets, which is acyclic as long as the
+			// worksheets themselves are.
+			if dependent == field && !field.dependentsLocal[field] {
+				continue
+			}
+			if err := visit(dependent); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[field] = false
+		order = append(order, field)
+		return nil
+	}
+	for _, name := range names {
+		for _, field := range sortedFieldsByIndex(defs[name].(*Definition)) {
+			if err := visit(field); err != nil {
+				return err
+			}
+		}
+	}
+
+	rank := 0
+	for i := len(order) - 1; i >= 0; i-- {
+		if order[i].computedBy != nil {
+			rank++
+			order[i].rank = rank
+		}
+	}
+	return nil
+}
+
+// sortedDependents sorts dependents by worksheet name, and field index, such
+// that ranks, and cycles reported, do not depend on the order in which
+// dependents were registered.
+func sortedDependents(field *Field) []*Field {
+	dependents := field.Dependents()
+	sort.Slice(dependents, func(i, j int) bool {
+		if dependents[i].def.name != dependents[j].def.name {
+			return dependents[i].def.name < dependents[j].def.name
+		}
+		return dependents[i].index < dependents[j].index
+	})
+	return dependents
+}
+
+// cycleError reports the cycle closed by reaching field again from the end of
+// path. Since path follows dependents, the cycle is reversed to list each
+// field before the field it depends on.
+func cycleError(path []*Field, field *Field) error {
+	var start int
+	for i := range path {
+		if path[i] == field {
+			start = i
+			break
+		}
+	}
+	names := []string{field.qualifiedName()}
+	for i := len(path) - 1; start <= i; i-- {
+		names = append(names, path[i].qualifiedName())
+	}
+	return fmt.Errorf("cyclic dependency: %s", strings.Join(names, " -> "))
+}
+
 func (s tSelector) Select(elemType Type) ([]*Field, bool) {
 	switch typ := elemType.(type) {
 	case *Definition:
@@ -274,6 +471,26 @@ func processOptions(defs map[string]NamedType, opts ...Options) error {
 			return err
 		}
 	}
+
+	for name, editors := range opt.Editors {
+		typ, ok := defs[name]
+		if !ok {
+			return fmt.Errorf("editors: unknown worksheet %s", name)
+		}
+		def, ok := typ.(*Definition)
+		if !ok {
+			return fmt.Errorf("editors: unknown worksheet %s", name)
+		}
+		def.editors = append(def.editors, editors...)
+	}
+
+	if opt.Concurrent {
+		for _, typ := range defs {
+			if def, ok := typ.(*Definition); ok {
+				def.concurrent = true
+			}
+		}
+	}
 	return nil
 }
 
@@ -309,29 +526,43 @@ func (defs *Definitions) NewWorksheet(name string) (*Worksheet, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ws.initialize()
+}
 
+// initialize assigns a new worksheet its id, and version, and computes its
+// computed fields.
+func (ws *Worksheet) initialize() (*Worksheet, error) {
 	// uuid
 	id := uuid.Must(uuid.NewV4())
 
-	if err := ws.Set("id", NewText(id.String())); err != nil {
+	tx := newEditTx()
+	if err := ws.set(tx, ws.def.fieldsByIndex[indexId], NewText(id.String())); err != nil {
 		panic(fmt.Sprintf("unexpected %s", err))
 	}
+	ws.id = id.String()
 
 	// version
-	if err := ws.Set("version", NewNumberFromInt(1)); err != nil {
+	if err := ws.set(tx, ws.def.fieldsByIndex[indexVersion], NewNumberFromInt(1)); err != nil {
 		panic(fmt.Sprintf("unexpected %s", err))
 	}
 
-	// computedBy
+	// computedBy, in rank order such that each field is computed once
+	var computed []*Field
 	for _, field := range ws.def.fieldsByIndex {
 		if field.computedBy != nil {
-			value, err := field.computedBy.compute(ws)
-			if err != nil {
-				return nil, err
-			}
-			ws.set(field, value)
+			computed = append(computed, field)
 		}
 	}
+	sort.Slice(computed, func(i, j int) bool {
+		return computed[i].rank < computed[j].rank
+	})
+	for _, field := range computed {
+		value, err := field.computedBy.compute(ws)
+		if err != nil {
+			return nil, err
+		}
+		ws.set(tx, field, value)
+	}
 
 	return ws, nil
 }
@@ -350,19 +581,28 @@ func (defs *Definitions) newUninitializedWorksheet(name string) (*Worksheet, err
 }
 
 func (def *Definition) newUninitializedWorksheet() *Worksheet {
-	return &Worksheet{
+	ws := &Worksheet{
 		def:     def,
 		orig:    make(map[int]Value),
 		data:    make(map[int]Value),
 		parents: make(map[string]map[int]map[string]*Worksheet),
 	}
+	if def.concurrent {
+		ws.graph = newGraphLock()
+	}
+	return ws
 }
 
 func (ws *Worksheet) Id() string {
-	return ws.data[indexId].(*Text).value
+	return ws.id
 }
 
 func (ws *Worksheet) Version() int {
+	defer ws.rlock()()
+	return ws.version()
+}
+
+func (ws *Worksheet) version() int {
 	return int(ws.data[indexVersion].(*Number).value)
 }
 
@@ -377,58 +617,51 @@ func (ws *Worksheet) MustSet(name string, value Value) {
 	}
 }
 
+// Set sets the field `name` to `value`. Setting a field is an edit of its own,
+// see `Edit.Apply`: should it, or any recomputation it causes, fail, the
+// worksheet, and its parents, are left untouched.
 func (ws *Worksheet) Set(name string, value Value) error {
-	// TODO(pascal): create a 'change', and then commit that change, garantee
-	// that commits are atomic, and either win or lose the race by using
-	// optimistic concurrency. Change must be a a Definition level, since it
-	// could span multiple worksheets at once.
+	return NewEdit().Set(name, value).Apply(ws)
+}
 
+// fieldForSet looks up the field `name`, and verifies that it can be set.
+func (ws *Worksheet) fieldForSet(name string) (*Field, error) {
 	// lookup field by name
 	field, ok := ws.def.fieldsByName[name]
 	if !ok {
-		return fmt.Errorf("unknown field %s", name)
+		return nil, &UnknownField{ws, name}
 	}
 
 	if field.computedBy != nil {
-		return fmt.Errorf("cannot assign to computed field %s", name)
+		return nil, &ComputedFieldAssignment{ws, name}
 	}
 
 	if _, ok := field.typ.(*SliceType); ok {
-		return fmt.Errorf("Set on slice field %s, use Append, or Del", name)
+		return nil, fmt.Errorf("Set on slice field %s, use Append, or Del", name)
 	}
 
-	if field.constrainedBy != nil {
-		prevValue := ws.MustGet(name)
-
-		// plan rollback
-		hasFailed := true
-		defer func() {
-			if hasFailed {
-				ws.set(field, prevValue)
-			}
-		}()
+	return field, nil
+}
 
-		err := ws.set(field, value)
-		if err != nil {
-			return err
-		}
-		constrainedByResult, err := field.constrainedBy.compute(ws)
-		if err != nil {
-			return err
-		}
-		if val, ok := constrainedByResult.(*Bool); ok && val.value {
-			hasFailed = false
-			return nil
-		} else {
-			return fmt.Errorf("%s not a valid value for constrained field %s", value.String(), name)
-		}
+// checkConstraint verifies that the value of a constrained field, which has
+// just been set to `value`, satisfies the field's constraint.
+func (ws *Worksheet) checkConstraint(field *Field, value Value) error {
+	constrainedByResult, err := field.constrainedBy.compute(ws)
+	if err != nil {
+		return err
+	}
+	if val, ok := constrainedByResult.(*Bool); ok && val.value {
+		return nil
+	}
+	return &ConstraintViolation{
+		Worksheet: ws,
+		Field:     field.name,
+		Value:     value,
+		Message:   ws.interpolate(field.constraintMessage),
 	}
-
-	err := ws.set(field, value)
-	return err
 }
 
-func (ws *Worksheet) set(field *Field, value Value) error {
+func (ws *Worksheet) set(tx *editTx, field *Field, value Value) error {
 	var (
 		index          = field.index
 		_, isUndefined = value.(*Undefined)
@@ -447,10 +680,11 @@ func (ws *Worksheet) set(field *Field, value Value) error {
 
 	// assignability check
 	if err := canAssignTo("assign", value, field.typ); err != nil {
-		return err
+		return withField(err, ws, field)
 	}
 
 	// store
+	tx.touch(ws)
 	if isUndefined {
 		delete(ws.data, index)
 	} else {
@@ -458,9 +692,7 @@ func (ws *Worksheet) set(field *Field, value Value) error {
 	}
 
 	// dependents
-	if err := ws.handleDependentUpdates(field, oldValue, value); err != nil {
-		return err
-	}
+	ws.handleDependentUpdates(tx, field, oldValue, value)
 
 	return nil
 }
@@ -472,12 +704,7 @@ func (ws *Worksheet) MustUnset(name string) {
 }
 
 func (ws *Worksheet) Unset(name string) error {
-	if field, ok := ws.def.fieldsByName[name]; ok {
-		if _, ok := field.typ.(*SliceType); ok {
-			return fmt.Errorf("Unset on slice field names, must use Del")
-		}
-	}
-	return ws.Set(name, NewUndefined())
+	return NewEdit().Unset(name).Apply(ws)
 }
 
 func (ws *Worksheet) MustIsSet(name string) bool {
@@ -489,10 +716,12 @@ func (ws *Worksheet) MustIsSet(name string) bool {
 }
 
 func (ws *Worksheet) IsSet(name string) (bool, error) {
+	defer ws.rlock()()
+
 	// lookup field by name
 	field, ok := ws.def.fieldsByName[name]
 	if !ok {
-		return false, fmt.Errorf("unknown field %s", name)
+		return false, &UnknownField{ws, name}
 	}
 	index := field.index
 
@@ -519,6 +748,8 @@ func (ws *Worksheet) MustGetSlice(name string) []Value {
 }
 
 func (ws *Worksheet) GetSlice(name string) ([]Value, error) {
+	defer ws.rlock()()
+
 	_, slice, err := ws.getSlice(name)
 	if err != nil {
 		return nil, err
@@ -546,6 +777,8 @@ func (ws *Worksheet) getSlice(name string) (*Field, *Slice, error) {
 // Get gets a value for base types, e.g. text, number, or bool.
 // For other kinds of values, use specific getters such as `GetSlice`.
 func (ws *Worksheet) Get(name string) (Value, error) {
+	defer ws.rlock()()
+
 	field, value, err := ws.get(name)
 	if err != nil {
 		return nil, err
@@ -562,7 +795,7 @@ func (ws *Worksheet) get(name string) (*Field, Value, error) {
 	// lookup field by name
 	field, ok := ws.def.fieldsByName[name]
 	if !ok {
-		return nil, nil, fmt.Errorf("unknown field %s", name)
+		return nil, nil, &UnknownField{ws, name}
 	}
 	index := field.index
 
@@ -585,20 +818,35 @@ func (ws *Worksheet) MustAppend(name string, value Value) {
 	}
 }
 
+// Append appends `element` to the slice field `name`. Like `Set`, this is an
+// edit of its own.
 func (ws *Worksheet) Append(name string, element Value) error {
+	return NewEdit().Append(name, element).Apply(ws)
+}
+
+// fieldForAppend looks up the field `name`, and verifies that it is a slice.
+func (ws *Worksheet) fieldForAppend(name string) (*Field, error) {
 	// lookup field by name
 	field, ok := ws.def.fieldsByName[name]
 	if !ok {
-		return fmt.Errorf("unknown field %s", name)
+		return nil, &UnknownField{ws, name}
 	}
-	index := field.index
 
-	sliceType, ok := field.typ.(*SliceType)
-	if !ok {
-		return fmt.Errorf("Append on non-slice field %s", name)
+	if _, ok := field.typ.(*SliceType); !ok {
+		return nil, fmt.Errorf("Append on non-slice field %s", name)
 	}
 
+	return field, nil
+}
+
+func (ws *Worksheet) append(tx *editTx, field *Field, element Value) error {
+	var (
+		index        = field.index
+		sliceType, _ = field.typ.(*SliceType)
+	)
+
 	// is a value set for this field?
+	tx.touch(ws)
 	value, ok := ws.data[index]
 	if !ok {
 		value = newSlice(sliceType)
@@ -609,14 +857,12 @@ func (ws *Worksheet) Append(name string, element Value) error {
 	slice := value.(*Slice)
 	slice, err := slice.doAppend(element)
 	if err != nil {
-		return err
+		return withField(err, ws, field)
 	}
 	ws.data[index] = slice
 
 	// dependents
-	if err := ws.handleDependentUpdates(field, nil, element); err != nil {
-		return err
-	}
+	ws.handleDependentUpdates(tx, field, nil, element)
 
 	return nil
 }
@@ -627,94 +873,157 @@ func (ws *Worksheet) MustDel(name string, index int) {
 	}
 }
 
+// Del deletes the element at `index` of the slice field `name`. Like `Set`,
+// this is an edit of its own.
 func (ws *Worksheet) Del(name string, index int) error {
-	field, slice, err := ws.getSlice(name)
+	return NewEdit().Del(name, index).Apply(ws)
+}
+
+// fieldForDel looks up the field `name`, and verifies that it is a slice.
+func (ws *Worksheet) fieldForDel(name string) (*Field, error) {
+	field, _, err := ws.getSlice(name)
 	if err != nil {
 		if field != nil {
 			if _, ok := field.typ.(*SliceType); !ok {
-				return fmt.Errorf("Del on non-slice field %s", name)
+				return nil, fmt.Errorf("Del on non-slice field %s", name)
 			}
 		}
-		return err
+		return nil, err
 	}
+	return field, nil
+}
 
-	newSlice, err := slice.doDel(index)
-	if err != nil {
-		return err
+func (ws *Worksheet) del(tx *editTx, field *Field, index int) error {
+	return ws.updateSlice(tx, field, func(slice *Slice) (*Slice, error) {
+		return slice.doDel(index)
+	})
+}
+
+func (ws *Worksheet) MustInsert(name string, index int, element Value) {
+	if err := ws.Insert(name, index, element); err != nil {
+		panic(err)
 	}
-	deletedValue := slice.elements[index].value
-	ws.data[field.index] = newSlice
+}
 
-	// dependents
-	if err := ws.handleDependentUpdates(field, deletedValue, nil); err != nil {
-		return err
+// Insert inserts `element` before the element at `index` of the slice field
+// `name`, or appends it if `index` is the length of the slice. Like `Set`,
+// this is an edit of its own.
+func (ws *Worksheet) Insert(name string, index int, element Value) error {
+	return NewEdit().Insert(name, index, element).Apply(ws)
+}
+
+func (ws *Worksheet) MustSetAt(name string, index int, element Value) {
+	if err := ws.SetAt(name, index, element); err != nil {
+		panic(err)
 	}
+}
 
-	return nil
+// SetAt replaces the element at `index` of the slice field `name`. Like `Set`,
+// this is an edit of its own.
+func (ws *Worksheet) SetAt(name string, index int, element Value) error {
+	return NewEdit().SetAt(name, index, element).Apply(ws)
 }
 
-func (ws *Worksheet) handleDependentUpdates(field *Field, oldValue, newValue Value) error {
-	for _, dependentField := range field.dependents {
-		// 1. Gather all dependent worksheets which point to this worksheet,
-		// and need to be triggered.
-		var allDependents []*Worksheet
-		if dependentField.def == ws.def {
-			allDependents = []*Worksheet{ws}
-		} else {
-			for _, parentsByFieldIndex := range ws.parents[dependentField.def.name] {
-				for _, parent := range parentsByFieldIndex {
-					allDependents = append(allDependents, parent)
-				}
-			}
-		}
+func (ws *Worksheet) MustMove(name string, from, to int) {
+	if err := ws.Move(name, from, to); err != nil {
+		panic(err)
+	}
+}
 
-		// 2. Trigger the compute by of all dependent worksheets.
-		for _, dependent := range allDependents {
-			updatedValue, err := dependentField.computedBy.compute(dependent)
-			if err != nil {
-				return err
-			}
-			if err := dependent.set(dependentField, updatedValue); err != nil {
-				return err
+// Move moves the element at `from` of the slice field `name`, such that it is
+// at `to` once moved. Like `Set`, this is an edit of its own.
+func (ws *Worksheet) Move(name string, from, to int) error {
+	return NewEdit().Move(name, from, to).Apply(ws)
+}
+
+func (ws *Worksheet) MustClear(name string) {
+	if err := ws.Clear(name); err != nil {
+		panic(err)
+	}
+}
+
+// Clear removes all elements of the slice field `name`. Like `Set`, this is
+// an edit of its own.
+func (ws *Worksheet) Clear(name string) error {
+	return NewEdit().Clear(name).Apply(ws)
+}
+
+// fieldForSlice looks up the field `name`, and verifies that it is a slice,
+// for operation `op`.
+func (ws *Worksheet) fieldForSlice(op, name string) (*Field, error) {
+	field, ok := ws.def.fieldsByName[name]
+	if !ok {
+		return nil, &UnknownField{ws, name}
+	}
+
+	if _, ok := field.typ.(*SliceType); !ok {
+		return nil, fmt.Errorf("%s on non-slice field %s", op, name)
+	}
+
+	return field, nil
+}
+
+// updateSlice replaces the slice of the field by the result of `fn`, and
+// updates parent pointers of elements removed from, or added to the slice.
+func (ws *Worksheet) updateSlice(tx *editTx, field *Field, fn func(slice *Slice) (*Slice, error)) error {
+	_, slice, err := ws.getSlice(field.name)
+	if err != nil {
+		return err
+	}
+
+	updated, err := fn(slice)
+	if err != nil {
+		return withField(err, ws, field)
+	}
+	tx.touch(ws)
+	ws.data[field.index] = updated
+
+	// dependents, and parent pointers of elements removed, or added, where
+	// worksheets may still be in the slice, e.g. if moved, or repeated
+	diff := diffSlices(slice, updated)
+	for _, element := range diff.added {
+		ws.handleDependentUpdates(tx, field, nil, element.value)
+	}
+	remaining := make(map[*Worksheet]bool)
+	for _, childWs := range extractChildWs(updated) {
+		remaining[childWs] = true
+	}
+	for _, element := range diff.deleted {
+		for _, childWs := range extractChildWs(element.value) {
+			if !remaining[childWs] {
+				ws.handleDependentUpdates(tx, field, childWs, nil)
 			}
 		}
 	}
+	tx.markDependents(ws, field)
 
+	return nil
+}
+
+func (ws *Worksheet) handleDependentUpdates(tx *editTx, field *Field, oldValue, newValue Value) {
 	// Add ws to parent pointers of newValue.
 	for _, childWs := range extractChildWs(newValue) {
+		tx.touch(childWs)
 		childWs.parents.addParentViaFieldIndex(ws, field.index)
 	}
 
 	// Remove ws from parent pointers of oldValue.
 	for _, childWs := range extractChildWs(oldValue) {
+		tx.touch(childWs)
 		childWs.parents.removeParentViaFieldIndex(ws, field.index)
 	}
 
-	return nil
+	// Dependents are recomputed once all edits are done, in topological
+	// order, see `editTx.recompute`.
+	tx.markDependents(ws, field)
 }
 
 func canAssignTo(op string, value Value, typ Type) error {
-	valueTyp := value.Type()
 	if !value.assignableTo(typ) {
-		var (
-			valueStr                 string
-			valueAsText, valueIsText = value.(*Text)
-			_, typIsEnum             = typ.(*EnumType)
-		)
-		if valueIsText && typIsEnum {
-			// We allow the value to leak into the error message in the special
-			// case of assigning a text to an enum.
-			valueStr = valueAsText.value
-		} else {
-			valueStr = fmt.Sprintf("value of type %s", valueTyp)
-		}
-		switch op {
-		case "assign":
-			return fmt.Errorf("cannot %s %s to %s", op, valueStr, typ)
-		case "append":
-			return fmt.Errorf("cannot %s %s to []%s", op, valueStr, typ)
-		default:
-			panic("unexpected")
+		return &TypeMismatch{
+			Value: value,
+			Type:  typ,
+			op:    op,
 		}
 	}
 